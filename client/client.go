@@ -2,12 +2,12 @@ package client
 
 import (
 	"crypto/ecdsa"
+	"crypto/sha256"
 	"fmt"
 	"github.com/ipfs/go-log"
 	"math/big"
 	"os"
 	"path"
-	"strconv"
 	"time"
 
 	lib "github.com/bnb-chain/tss-lib/v2/common"
@@ -23,6 +23,8 @@ import (
 
 var Logger = log.Logger("tss")
 
+const deliveryTimeout = time.Minute // how long we wait for peers to acknowledge our last messages before shutdown
+
 type ClientMode uint8
 
 const (
@@ -60,10 +62,28 @@ type TssClient struct {
 	signCh chan lib.SignatureData
 	sendCh chan tss.Message
 
+	stopSending    chan struct{} // closed once local party ends, sendMessageRoutine hands what is left in sendCh to transporter and exits
+	sendingStopped chan struct{}
+	failed         chan error // the first error of routines, which ends the session
+
 	mode ClientMode
 }
 
-func NewTssClient(config *common.TssConfig, mode ClientMode, mock bool) *TssClient {
+// NewTssClient creates a client talking over transporter, a p2p transporter is created (blocks until peers are connected) if it is nil.
+// Tests pass transporters of a p2p.MemNet
+func NewTssClient(config *common.TssConfig, mode ClientMode, transporter common.Transporter) (*TssClient, error) {
+	return newTssClient(config, mode, transporter, nil, "")
+}
+
+// NewSessionTssClient creates a client running session sessionId over h, a host of the vault shared with other sessions
+// (see p2p.NewSessionTransporter), it blocks until peers of the session are connected.
+// There is no bootstrapping on a shared host, signers (and new committee in regroup) are taken from config.
+// An error only fails this session (i.e. peers are not connected within config.BootstrapTimeout), the host is left for others
+func NewSessionTssClient(config *common.TssConfig, mode ClientMode, h *p2p.Host, sessionId string) (*TssClient, error) {
+	return newTssClient(config, mode, nil, h, sessionId)
+}
+
+func newTssClient(config *common.TssConfig, mode ClientMode, transporter common.Transporter, h *p2p.Host, sessionId string) (*TssClient, error) {
 	id := string(config.Id)
 	idToPartyIds := make(map[string]*tss.PartyID)
 	key := lib.SHA512_256([]byte(id)) // TODO: discuss should we really need pass p2p nodeid pubkey into NewPartyID? (what if in memory implementation)
@@ -71,7 +91,7 @@ func NewTssClient(config *common.TssConfig, mode ClientMode, mock bool) *TssClie
 	idToPartyIds[id] = partyID
 	unsortedPartyIds := make(tss.UnSortedPartyIDs, 0, config.Parties)
 	if mode == RegroupMode {
-		if common.TssCfg.IsOldCommittee {
+		if config.IsOldCommittee {
 			unsortedPartyIds = append(unsortedPartyIds, partyID)
 		}
 	} else {
@@ -82,86 +102,82 @@ func NewTssClient(config *common.TssConfig, mode ClientMode, mock bool) *TssClie
 	signers := make(map[string]int, 0) // used by sign and regroup mode for filtering correct shares from LocalPartySaveData, including self
 	if mode != KeygenMode {
 		if mode == SignMode {
-			common.TssCfg.BMode = common.SignMode
+			config.BMode = common.SignMode
 		}
 		if mode == RegroupMode {
-			common.TssCfg.BMode = common.RegroupMode
-		}
-		bootstrapper := common.NewBootstrapper(0, &common.TssCfg)
-		t := p2p.NewP2PTransporter(config.Home, config.Vault, config.Id.String(), bootstrapper, nil, nil, signers, &config.P2PConfig)
-		t.Shutdown()
-		bootstrapper.Peers.Range(func(_, value interface{}) bool {
-			if pi, ok := value.(common.PeerInfo); ok {
-				if mode == SignMode || (mode == RegroupMode && pi.IsOld) {
-					signers[pi.Moniker] = 0
-				}
-
-				if mode == RegroupMode {
-					if pi.IsNew {
-						// we don't know whether pi's info has been updated during raw tcp bootstrapping
-						config.ExpectedNewPeers = appendIfNotExist(config.ExpectedNewPeers, fmt.Sprintf("%s@%s", pi.Moniker, pi.Id))
-						config.NewPeerAddrs = appendIfNotExist(config.NewPeerAddrs, pi.RemoteAddr)
-					}
+			config.BMode = common.RegroupMode
+		}
+		if config.UseManifest {
+			// signers and new committee have been applied to config from manifest
+			manifest, err := common.LoadImportedManifest(config.Home, config.Vault)
+			if err != nil {
+				return nil, err
+			}
+			for _, moniker := range manifest.Signers() {
+				signers[moniker] = 0
+			}
+		} else if transporter != nil || h != nil {
+			// parties on an injected transporter (i.e. in memory) or a shared host are not bootstrapped, config tells who are signing (old committee in regroup)
+			for _, moniker := range config.Signers {
+				signers[moniker] = 0
+			}
+		} else {
+			if mode == SignMode {
+				// only chosen signers are connected, see Bootstrapper.IsFinished
+				for _, moniker := range config.Signers {
+					signers[moniker] = 0
 				}
 			}
-			return true
-		})
-		if mode == SignMode || (mode == RegroupMode && common.TssCfg.IsOldCommittee) {
+			if err := bootstrapSigners(config, mode, signers); err != nil {
+				return nil, err
+			}
+		}
+		if mode == SignMode || (mode == RegroupMode && config.IsOldCommittee) {
 			signers[config.Moniker] = 0
 		}
 
 		if len(signers) < config.Threshold+1 {
-			common.Panic(fmt.Errorf("no enough signers (%d) to meet requirement: %d", len(signers), config.Threshold+1))
+			return nil, fmt.Errorf("no enough signers (%d) to meet requirement: %d", len(signers), config.Threshold+1)
 		}
-		updatePeerOriginalIndexes(config, bootstrapper, partyID, signers)
+		updatePeerOriginalIndexes(config, partyID, signers)
 	}
 
-	if !mock {
-		for _, peer := range config.P2PConfig.ExpectedPeers {
+	for _, peer := range config.P2PConfig.ExpectedPeers {
+		id := string(p2p.GetClientIdFromExpectedPeers(peer))
+		moniker := p2p.GetMonikerFromExpectedPeers(peer)
+		key := lib.SHA512_256([]byte(id))
+		if mode == SignMode || mode == RegroupMode {
+			if _, ok := signers[moniker]; !ok {
+				continue
+			}
+		}
+		partyId := tss.NewPartyID(
+			id,
+			moniker,
+			new(big.Int).SetBytes(key))
+		idToPartyIds[id] = partyId
+		unsortedPartyIds = append(unsortedPartyIds, partyId)
+	}
+	if mode == RegroupMode {
+		for _, peer := range config.P2PConfig.ExpectedNewPeers {
 			id := string(p2p.GetClientIdFromExpectedPeers(peer))
 			moniker := p2p.GetMonikerFromExpectedPeers(peer)
 			key := lib.SHA512_256([]byte(id))
-			if mode == SignMode || mode == RegroupMode {
-				if _, ok := signers[moniker]; !ok {
-					continue
-				}
-			}
-			partyId := tss.NewPartyID(
-				id,
-				moniker,
-				new(big.Int).SetBytes(key))
-			idToPartyIds[id] = partyId
-			unsortedPartyIds = append(unsortedPartyIds, partyId)
-		}
-		if mode == RegroupMode {
-			for _, peer := range config.P2PConfig.ExpectedNewPeers {
-				id := string(p2p.GetClientIdFromExpectedPeers(peer))
-				moniker := p2p.GetMonikerFromExpectedPeers(peer)
-				key := lib.SHA512_256([]byte(id))
-				if moniker != config.Moniker {
-					partyId := tss.NewPartyID(
-						id,
-						moniker,
-						new(big.Int).SetBytes(key))
-					idToPartyIds[id] = partyId
-					unsortedNewPartyIds = append(unsortedNewPartyIds, partyId)
-				}
-			}
-			if !config.IsOldCommittee {
-				unsortedNewPartyIds = append(unsortedNewPartyIds, partyID)
+			if moniker != config.Moniker {
+				partyId := tss.NewPartyID(
+					id,
+					moniker,
+					new(big.Int).SetBytes(key))
+				idToPartyIds[id] = partyId
+				unsortedNewPartyIds = append(unsortedNewPartyIds, partyId)
 			}
 		}
-	} else {
-		for i := 0; i < config.Parties; i++ {
-			id, _ := strconv.Atoi(string(config.Id))
-			if i != id {
-				id := strconv.Itoa(i)
-				key := lib.SHA512_256([]byte(id))
-				unsortedPartyIds = append(unsortedPartyIds, tss.NewPartyID(id, id, new(big.Int).SetBytes(key)))
-			}
+		if !config.IsOldCommittee {
+			unsortedNewPartyIds = append(unsortedNewPartyIds, partyID)
 		}
 	}
 	sortedIds := tss.SortPartyIDs(unsortedPartyIds)
+	var sortedNewIds tss.SortedPartyIDs
 	p2pCtx := tss.NewPeerContext(sortedIds)
 	saveCh := make(chan keygen.LocalPartySaveData)
 	signCh := make(chan lib.SignatureData)
@@ -174,29 +190,40 @@ func NewTssClient(config *common.TssConfig, mode ClientMode, mock bool) *TssClie
 		signCh: signCh,
 		sendCh: sendCh,
 
+		stopSending:    make(chan struct{}),
+		sendingStopped: make(chan struct{}),
+		failed:         make(chan error, 1),
+
 		mode: mode,
 	}
 
 	var localParty tss.Party
 	if mode == KeygenMode {
 		params := tss.NewParameters(tss.EC(), p2pCtx, partyID, config.Parties, config.Threshold)
-		localParty = keygen.NewLocalParty(params, sendCh, saveCh)
+		if preParams := loadPreParams(config); preParams != nil {
+			localParty = keygen.NewLocalParty(params, sendCh, saveCh, *preParams)
+		} else {
+			localParty = keygen.NewLocalParty(params, sendCh, saveCh)
+		}
 		c.localParty = localParty
 		Logger.Infof("[%s] initialized localParty: %s", config.Moniker, localParty)
 	} else if mode == SignMode {
-		key := loadSavedKeyForSign(config, sortedIds, signers)
+		key, err := loadSavedKeyForSign(config, sortedIds, signers)
+		if err != nil {
+			return nil, err
+		}
 		pubKey := btcec.PublicKey(ecdsa.PublicKey{tss.EC(), key.ECDSAPub.X(), key.ECDSAPub.Y()})
 		Logger.Infof("[%s] public key: %X\n", config.Moniker, pubKey.SerializeCompressed())
 		address, err := GetAddress(ecdsa.PublicKey{tss.EC(), key.ECDSAPub.X(), key.ECDSAPub.Y()}, config.AddressPrefix)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
 		Logger.Debugf("[%s] address is: %s\n", config.Moniker, address)
 		params := tss.NewParameters(tss.EC(), p2pCtx, partyID, config.Parties, config.Threshold)
 		c.key = &key
 		c.params = params
 	} else if mode == RegroupMode {
-		sortedNewIds := tss.SortPartyIDs(unsortedNewPartyIds)
+		sortedNewIds = tss.SortPartyIDs(unsortedNewPartyIds)
 		newP2pCtx := tss.NewPeerContext(sortedNewIds)
 		params := tss.NewReSharingParameters(
 			tss.EC(),
@@ -209,74 +236,121 @@ func NewTssClient(config *common.TssConfig, mode ClientMode, mock bool) *TssClie
 			config.NewThreshold)
 		c.regroupParams = params
 
-		if _, ok := signers[common.TssCfg.Moniker]; ok {
-			key := loadSavedKeyForRegroup(config, sortedIds, signers)
+		if _, ok := signers[config.Moniker]; ok {
+			key, err := loadSavedKeyForRegroup(config, sortedIds, signers)
+			if err != nil {
+				return nil, err
+			}
 			c.key = &key
 			localParty = resharing.NewLocalParty(params, key, sendCh, saveCh)
 		} else {
 			// TODO do this better!
-			save := newEmptySaveData()
+			save := newEmptySaveData(config)
+			if preParams := loadPreParams(config); preParams != nil {
+				save.LocalPreParams = *preParams
+			}
 			localParty = resharing.NewLocalParty(params, save, sendCh, saveCh)
 		}
 		c.localParty = localParty
 	}
 
-	if mock {
-		c.transporter = p2p.GetMemTransporter(config.Id)
+	var err error
+	if transporter != nil {
+		c.transporter = transporter
+	} else if h != nil {
+		// will block until peers are connected
+		agreement := NewCommitteeAgreement(config, mode, sortedIds, sortedNewIds)
+		if c.transporter, err = p2p.NewSessionTransporter(h, sessionId, nil, c.params, c.regroupParams, agreement, signers, config); err != nil {
+			return nil, err
+		}
 	} else {
 		// will block until peers are connected
-		c.transporter = p2p.NewP2PTransporter(
-			config.Home,
-			config.Vault,
-			config.Id.String(),
-			nil,
-			c.params,
-			c.regroupParams,
-			signers,
-			&config.P2PConfig)
+		agreement := NewCommitteeAgreement(config, mode, sortedIds, sortedNewIds)
+		if c.transporter, err = p2p.NewP2PTransporter(nil, c.params, c.regroupParams, agreement, signers, config); err != nil {
+			return nil, err
+		}
 	}
 
-	return &c
+	return &c, nil
 }
 
-func (client *TssClient) Start() {
+// Start runs the session till local party ends, it returns the error ending the session early
+// (i.e. a peer is lost or misbehaves), peers are left to find out themselves
+func (client *TssClient) Start() error {
 	switch client.mode {
 	case SignMode:
 		message, ok := big.NewInt(0).SetString(client.config.Message, 10)
 		if !ok {
-			common.Panic(fmt.Errorf("message to be sign: %s is not a valid big.Int", client.config.Message))
+			return client.abort(fmt.Errorf("message to be sign: %s is not a valid big.Int", client.config.Message))
+		}
+		if _, err := client.signImpl(message); err != nil {
+			return client.abort(err)
 		}
-		client.signImpl(message)
-		time.Sleep(5 * time.Second)
 	default:
 		if err := client.localParty.Start(); err != nil {
-			common.Panic(err)
+			return client.abort(err)
 		}
 		done := make(chan bool)
 		go client.sendMessageRoutine(client.sendCh)
 		go client.saveDataRoutine(client.saveCh, done)
 		//go c.sendDummyMessageRoutine()
 		go client.handleMessageRoutine()
-		<-done
+		go client.handleControlRoutine()
+		select {
+		case <-done:
+		case err := <-client.failed:
+			return client.abort(err)
+		}
+	}
+	client.waitForDelivery()
+	// tell peers we are done, so that they don't wait for us to reconnect
+	if err := client.transporter.Shutdown(); err != nil {
+		Logger.Warningf("failed to shutdown transporter: %v", err)
 	}
+	return nil
+}
+
+// abort shuts down transporter without waiting for delivery and returns err
+func (client *TssClient) abort(err error) error {
+	if err := client.transporter.Shutdown(); err != nil {
+		Logger.Warningf("failed to shutdown transporter: %v", err)
+	}
+	return err
+}
+
+// fail ends the session with err, only the first error is kept, it doesn't block
+func (client *TssClient) fail(err error) {
+	select {
+	case client.failed <- err:
+	default:
+		Logger.Debugf("[%s] %v", client.config.Moniker, err)
+	}
+}
+
+// Signature returns signature (32 bytes r followed by 32 bytes s) once Start of sign mode returns
+func (client *TssClient) Signature() []byte {
+	return client.signature
 }
 
 func (client *TssClient) handleMessageRoutine() {
 	for msg := range client.transporter.ReceiveCh() {
 		var messageWrapper tss.MessageWrapper
 		if err := proto.Unmarshal(msg.MessageWrapperBytes, &messageWrapper); err != nil {
-			common.Panic(fmt.Errorf("[%s] error updating local party state: %v", client.config.Moniker, err))
+			client.fail(fmt.Errorf("[%s] error updating local party state: %v", client.config.Moniker, err))
+			return
 		}
 		any, err := proto.Marshal(messageWrapper.Message)
 		if err != nil {
-			common.Panic(fmt.Errorf("[%s] failed to extract message inside message wrapper: %v", client.config.Moniker, err))
+			client.fail(fmt.Errorf("[%s] failed to extract message inside message wrapper: %v", client.config.Moniker, err))
+			return
 		}
 		ok, err := client.localParty.UpdateFromBytes(
 			any,
 			client.idToPartyIds[messageWrapper.From.Id],
 			messageWrapper.IsBroadcast)
 		if !ok && err != nil {
-			common.Panic(fmt.Errorf("[%s] error updating local party state: %v", client.config.Moniker, err))
+			client.fail(fmt.Errorf("[%s] error updating local party state: %v", client.config.Moniker, err))
+			return
 		} else if !ok {
 			Logger.Warningf("[%s] Update still waiting for round to finish", client.config.Moniker)
 		} else {
@@ -285,27 +359,67 @@ func (client *TssClient) handleMessageRoutine() {
 	}
 }
 
+// handleControlRoutine gives up the session once a peer cannot be reached or cannot be trusted, as the protocol cannot finish without it
+func (client *TssClient) handleControlRoutine() {
+	for msg := range client.transporter.ControlCh() {
+		if msg.Err != nil {
+			client.fail(fmt.Errorf("[%s] lost peer %s: %v", client.config.Moniker, msg.Peer, msg.Err))
+			return
+		}
+		Logger.Debugf("[%s] %d message(s) delivered to %s", client.config.Moniker, msg.Delivered, msg.Peer)
+	}
+}
+
+// waitForDelivery makes sure peers receive the last messages of local party before we shut down,
+// local party sends them before it ends, so they are either in sendCh or have been handed to transporter
+func (client *TssClient) waitForDelivery() {
+	close(client.stopSending)
+	<-client.sendingStopped
+	if err := client.transporter.WaitForDelivery(deliveryTimeout); err != nil {
+		Logger.Warningf("[%s] %v", client.config.Moniker, err)
+	}
+}
+
 func (client *TssClient) sendMessageRoutine(sendCh <-chan tss.Message) {
-	for msg := range sendCh {
-		dest := msg.GetTo()
-		if dest == nil || len(dest) > 1 {
-			err := client.transporter.Broadcast(msg)
-			if err != nil {
-				Logger.Errorf("failed to broadcast message: %v", err)
-			}
-		} else {
-			payload, err := proto.Marshal(msg.WireMsg())
-			if err != nil {
-				Logger.Error("failed to protobuf marshal the message wrapper: %v", err)
-			}
-			payload = append([]byte{p2p.MessagePrefix}, payload...)
-			if err = client.transporter.Send(payload, common.TssClientId(dest[0].Id)); err != nil {
-				Logger.Errorf("failed to send message: %v", err)
+	defer close(client.sendingStopped)
+	for {
+		select {
+		case msg := <-sendCh:
+			client.sendMessage(msg)
+		case <-client.stopSending:
+			for {
+				select {
+				case msg := <-sendCh:
+					client.sendMessage(msg)
+				default:
+					return
+				}
 			}
 		}
 	}
 }
 
+// sendMessage hands msg to transporter, peers cannot finish the protocol without our message, so there is no point to go on if it fails
+func (client *TssClient) sendMessage(msg tss.Message) {
+	dest := msg.GetTo()
+	if dest == nil || len(dest) > 1 {
+		err := client.transporter.Broadcast(msg)
+		if err != nil {
+			client.fail(fmt.Errorf("[%s] failed to broadcast message: %v", client.config.Moniker, err))
+		}
+	} else {
+		payload, err := proto.Marshal(msg.WireMsg())
+		if err != nil {
+			client.fail(fmt.Errorf("[%s] failed to protobuf marshal the message wrapper: %v", client.config.Moniker, err))
+			return
+		}
+		payload = append([]byte{p2p.MessagePrefix}, payload...)
+		if err = client.transporter.Send(payload, common.TssClientId(dest[0].Id)); err != nil {
+			client.fail(fmt.Errorf("[%s] failed to send message: %v", client.config.Moniker, err))
+		}
+	}
+}
+
 func (client *TssClient) saveDataRoutine(saveCh <-chan keygen.LocalPartySaveData, done chan<- bool) {
 	for msg := range saveCh {
 		// Used for debugging signature verification failed issue, never uncomment in production!
@@ -313,10 +427,8 @@ func (client *TssClient) saveDataRoutine(saveCh <-chan keygen.LocalPartySaveData
 		//ioutil.WriteFile(path.Join(client.config.Home, "plain.json"), plainJson, 0400)
 
 		if client.mode == RegroupMode {
-			if common.TssCfg.IsOldCommittee {
-				// wait for round_3 messages sent success before close old
-				// TODO: introduce a send callback to waiting here
-				time.Sleep(5 * time.Second)
+			if client.config.IsOldCommittee {
+				// old committee has nothing to save, Start waits for our round_3 messages to be delivered before shutdown
 				if done != nil {
 					done <- true
 					close(done)
@@ -334,17 +446,20 @@ func (client *TssClient) saveDataRoutine(saveCh <-chan keygen.LocalPartySaveData
 
 		wPriv, err := os.OpenFile(path.Join(client.config.Home, client.config.Vault, "sk.json"), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
 		if err != nil {
-			common.Panic(err)
+			client.fail(err)
+			return
 		}
 		defer wPriv.Close() // defer within loop is fine here as for one party there would be only one element from saveCh
 		wPub, err := os.OpenFile(path.Join(client.config.Home, client.config.Vault, "pk.json"), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
 		if err != nil {
-			common.Panic(err)
+			client.fail(err)
+			return
 		}
 		defer wPub.Close() // defer within loop is fine here as for one party there would be only one element from saveCh
 		err = common.Save(&msg, client.transporter.NodeKey(), client.config.KDFConfig, client.config.Password, wPriv, wPub)
 		if err != nil {
-			common.Panic(err)
+			client.fail(err)
+			return
 		}
 
 		if done != nil {
@@ -366,8 +481,65 @@ func (client *TssClient) saveSignatureRoutine(signCh <-chan lib.SignatureData, d
 	}
 }
 
+// bootstrapSigners finds online signers (and new committee in regroup) via libp2p bootstrapping
+func bootstrapSigners(config *common.TssConfig, mode ClientMode, signers map[string]int) error {
+	bootstrapper := common.NewBootstrapper(0, config)
+	t, err := p2p.NewP2PTransporter(bootstrapper, nil, nil, nil, signers, config)
+	if err != nil {
+		return err
+	}
+	t.Shutdown()
+	bootstrapper.Peers.Range(func(_, value interface{}) bool {
+		if pi, ok := value.(common.PeerInfo); ok {
+			if mode == SignMode || (mode == RegroupMode && pi.IsOld) {
+				signers[pi.Moniker] = 0
+			}
+
+			if mode == RegroupMode {
+				if pi.IsNew {
+					// we don't know whether pi's info has been updated during raw tcp bootstrapping
+					config.ExpectedNewPeers = appendIfNotExist(config.ExpectedNewPeers, fmt.Sprintf("%s@%s", pi.Moniker, pi.Id))
+					config.NewPeerAddrs = appendIfNotExist(config.NewPeerAddrs, pi.RemoteAddr)
+				}
+			}
+		}
+		return true
+	})
+	return nil
+}
+
+// NewCommitteeAgreement describes the committee this party is going to run the protocol with,
+// different committees (i.e. different signers picked during bootstrapping) would hang the protocol.
+// Programs creating transporters themselves (i.e. p2p.NewSessionTransporter) pass it to make peers check the committee
+func NewCommitteeAgreement(config *common.TssConfig, mode ClientMode, sortedIds, sortedNewIds tss.SortedPartyIDs) *p2p.CommitteeAgreement {
+	broadcastMode, err := config.P2PConfig.GetBroadcastMode()
+	if err != nil {
+		common.Panic(err)
+	}
+	agreement := &p2p.CommitteeAgreement{
+		Mode:          mode.String(),
+		Threshold:     int32(config.Threshold),
+		Parties:       int32(config.Parties),
+		PartyIds:      partyIdStrings(sortedIds),
+		BroadcastMode: broadcastMode,
+	}
+	switch mode {
+	case SignMode:
+		// digest of the number rather than the string, so that different representations of the same message agree
+		if message, ok := new(big.Int).SetString(config.Message, 10); ok {
+			digest := sha256.Sum256(message.Bytes())
+			agreement.MessageDigest = digest[:]
+		}
+	case RegroupMode:
+		agreement.NewThreshold = int32(config.NewThreshold)
+		agreement.NewParties = int32(config.NewParties)
+		agreement.NewPartyIds = partyIdStrings(sortedNewIds)
+	}
+	return agreement
+}
+
 // assign original keygen index to signers (old parties in regroup)
-func updatePeerOriginalIndexes(config *common.TssConfig, bootstrapper *common.Bootstrapper, partyID *tss.PartyID, signers map[string]int) {
+func updatePeerOriginalIndexes(config *common.TssConfig, partyID *tss.PartyID, signers map[string]int) {
 	allPartyIds := make(tss.UnSortedPartyIDs, 0, config.Parties) // all parties, used for calculating party's index during keygen
 	allPartyIds = append(allPartyIds, partyID)
 	for _, peer := range config.P2PConfig.ExpectedPeers {
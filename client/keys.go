@@ -5,7 +5,6 @@ import (
 	"encoding/hex"
 	"math/big"
 
-	"github.com/bgentry/speakeasy"
 	"github.com/bnb-chain/tss-lib/v2/ecdsa/signing"
 	"github.com/bnb-chain/tss-lib/v2/tss"
 	"github.com/btcsuite/btcd/btcec"
@@ -56,15 +55,20 @@ func (client *TssClient) signImpl(m *big.Int) ([]byte, error) {
 
 	// has to start local party before network routines in case 2 other peers' msg comes before self fully initialized
 	if err := client.localParty.Start(); err != nil {
-		common.Panic(err)
+		return nil, err
 	}
 
 	done := make(chan bool)
 	go client.sendMessageRoutine(client.sendCh)
 	go client.handleMessageRoutine()
+	go client.handleControlRoutine()
 	go client.saveSignatureRoutine(client.signCh, done)
 
-	<-done
+	select {
+	case <-done:
+	case err := <-client.failed:
+		return nil, err
+	}
 	Logger.Debugf("[%s] received signature: %X", client.config.Moniker, client.signature)
 	return client.signature, nil
 }
@@ -73,7 +77,7 @@ func (client *TssClient) signImpl(m *big.Int) ([]byte, error) {
 func LoadPubkey(home, vault string) (crypto.PubKey, error) {
 	passphrase := common.TssCfg.Password
 	if passphrase == "" {
-		if p, err := speakeasy.Ask("> Password to sign with this vault:"); err == nil {
+		if p, err := common.ReadSecret(common.TssCfg.PasswordSource, "Password to sign with this vault"); err == nil {
 			passphrase = p
 		} else {
 			return nil, err
@@ -94,7 +98,7 @@ func LoadPubkey(home, vault string) (crypto.PubKey, error) {
 func loadPubkeyAsCompressedHexString(home, vault string) (string, error) {
 	passphrase := common.TssCfg.Password
 	if passphrase == "" {
-		if p, err := speakeasy.Ask("> Password to sign with this vault:"); err == nil {
+		if p, err := common.ReadSecret(common.TssCfg.PasswordSource, "Password to sign with this vault"); err == nil {
 			passphrase = p
 		} else {
 			return "", err
@@ -3,6 +3,7 @@ package client
 import (
 	"crypto/ecdsa"
 	"crypto/sha256"
+	"fmt"
 	"math/big"
 	"os"
 	"path"
@@ -19,8 +20,11 @@ import (
 	"github.com/bnb-chain/tss/common"
 )
 
-func loadSavedKeyForSign(config *common.TssConfig, sortedIds tss.SortedPartyIDs, signers map[string]int) keygen.LocalPartySaveData {
-	result := loadSavedKey(config)
+func loadSavedKeyForSign(config *common.TssConfig, sortedIds tss.SortedPartyIDs, signers map[string]int) (keygen.LocalPartySaveData, error) {
+	result, err := loadSavedKey(config)
+	if err != nil {
+		return keygen.LocalPartySaveData{}, err
+	}
 	filteredBigXj := make([]*crypto.ECPoint, 0)
 	filteredPaillierPks := make([]*paillier.PublicKey, 0)
 	filteredNTildej := make([]*big.Int, 0)
@@ -56,11 +60,14 @@ func loadSavedKeyForSign(config *common.TssConfig, sortedIds tss.SortedPartyIDs,
 		ECDSAPub:    result.ECDSAPub,
 	}
 
-	return filteredResult
+	return filteredResult, nil
 }
 
-func loadSavedKeyForRegroup(config *common.TssConfig, sortedIds tss.SortedPartyIDs, signers map[string]int) keygen.LocalPartySaveData {
-	result := loadSavedKeyForSign(config, sortedIds, signers)
+func loadSavedKeyForRegroup(config *common.TssConfig, sortedIds tss.SortedPartyIDs, signers map[string]int) (keygen.LocalPartySaveData, error) {
+	result, err := loadSavedKeyForSign(config, sortedIds, signers)
+	if err != nil {
+		return result, err
+	}
 
 	if !config.IsOldCommittee {
 		// TODO: negotiate with Luke to see how to fill non-loaded keys here
@@ -73,36 +80,52 @@ func loadSavedKeyForRegroup(config *common.TssConfig, sortedIds tss.SortedPartyI
 			result.Ks = append(result.Ks, result.Ks[len(signers)-1])
 		}
 	}
-	return result
+	return result, nil
 }
 
-func loadSavedKey(config *common.TssConfig) keygen.LocalPartySaveData {
+func loadSavedKey(config *common.TssConfig) (keygen.LocalPartySaveData, error) {
 	wPriv, err := os.OpenFile(path.Join(config.Home, config.Vault, "sk.json"), os.O_RDONLY, 0400)
 	if err != nil {
-		common.Panic(err)
+		return keygen.LocalPartySaveData{}, err
 	}
 	defer wPriv.Close()
 	wPub, err := os.OpenFile(path.Join(config.Home, config.Vault, "pk.json"), os.O_RDONLY, 0400)
 	if err != nil {
-		common.Panic(err)
+		return keygen.LocalPartySaveData{}, err
 	}
 	defer wPub.Close()
 
 	result, _, err := common.Load(config.Password, wPriv, wPub) // TODO: validate nodeKey
 	if err != nil {
-		common.Panic(err)
+		return keygen.LocalPartySaveData{}, err
 	}
-	return *result
+	return *result, nil
 }
 
-func newEmptySaveData() keygen.LocalPartySaveData {
+func newEmptySaveData(config *common.TssConfig) keygen.LocalPartySaveData {
 	return keygen.LocalPartySaveData{
-		BigXj:       make([]*crypto.ECPoint, common.TssCfg.NewParties),
-		PaillierPKs: make([]*paillier.PublicKey, common.TssCfg.NewParties),
-		NTildej:     make([]*big.Int, common.TssCfg.NewParties),
-		H1j:         make([]*big.Int, common.TssCfg.NewParties),
-		H2j:         make([]*big.Int, common.TssCfg.NewParties),
+		BigXj:       make([]*crypto.ECPoint, config.NewParties),
+		PaillierPKs: make([]*paillier.PublicKey, config.NewParties),
+		NTildej:     make([]*big.Int, config.NewParties),
+		H1j:         make([]*big.Int, config.NewParties),
+		H2j:         make([]*big.Int, config.NewParties),
+	}
+}
+
+// take one pre params from vault's pool, returns nil if there is no available one
+// so that tss-lib would generate it inline
+func loadPreParams(config *common.TssConfig) *keygen.LocalPreParams {
+	preParams, err := common.TakePreParams(config.Home, config.Vault, config.Password)
+	if err != nil {
+		Logger.Warningf("[%s] failed to load pre params: %v", config.Moniker, err)
+		return nil
+	}
+	if preParams == nil {
+		Logger.Infof("[%s] no pre params in vault, generating paillier keys and safe primes might take several minutes (run `tss preparams` ahead to avoid this)", config.Moniker)
+		return nil
 	}
+	Logger.Infof("[%s] using pre-generated pre params, %d left in vault", config.Moniker, common.NumOfPreParams(config.Home, config.Vault))
+	return preParams
 }
 
 func appendIfNotExist(target []string, new string) []string {
@@ -137,3 +160,11 @@ func GetAddress(key ecdsa.PublicKey, prefix string) (string, error) {
 	}
 	return bech32.Encode(prefix, converted)
 }
+
+func partyIdStrings(sortedIds tss.SortedPartyIDs) []string {
+	ids := make([]string, 0, len(sortedIds))
+	for _, id := range sortedIds {
+		ids = append(ids, fmt.Sprintf("%s@%s", id.Moniker, id.Id))
+	}
+	return ids
+}
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"bufio"
-	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -10,16 +9,22 @@ import (
 	"strings"
 	"time"
 
-	"github.com/bgentry/speakeasy"
 	"github.com/spf13/cobra"
-	"google.golang.org/protobuf/proto"
 
 	"github.com/bnb-chain/tss/client"
 	"github.com/bnb-chain/tss/common"
+	"github.com/bnb-chain/tss/mdns"
 	"github.com/bnb-chain/tss/p2p"
 	"github.com/bnb-chain/tss/ssdp"
 )
 
+const (
+	rendezvousPollInterval = 2 * time.Second
+	// dialing a peer which is not up yet is retried from dialBackoff, doubled after each failure up to maxDialBackoff
+	dialBackoff    = time.Second
+	maxDialBackoff = 30 * time.Second
+)
+
 func init() {
 	rootCmd.AddCommand(bootstrapCmd)
 }
@@ -67,38 +72,16 @@ var bootstrapCmd = &cobra.Command{
 		done := make(chan bool)
 		go acceptConnRoutine(listener, bootstrapper, done)
 
-		peerAddrs := findPeerAddrsViaSsdp(numOfPeers, listenAddrs)
-		client.Logger.Debugf("Found peers via ssdp: %v", peerAddrs)
-
-		go func() {
-			for _, peerAddr := range peerAddrs {
-				go func(peerAddr string) {
-					dest, err := common.ConvertMultiAddrStrToNormalAddr(peerAddr)
-					if err != nil {
-						common.Panic(fmt.Errorf("failed to convert peer multiAddr to addr: %v", err))
-					}
-					client.Logger.Debugf("going to dial: %s", peerAddr)
-					conn, err := net.Dial("tcp", dest)
-					for conn == nil {
-						if err != nil {
-							if !strings.Contains(err.Error(), "connection refused") {
-								client.Logger.Errorf("dial failed: %v", err)
-								common.Panic(err)
-							}
-						}
-						time.Sleep(time.Second)
-						conn, err = net.Dial("tcp", dest)
-					}
-					client.Logger.Debugf("done dial: %s", peerAddr)
-					defer conn.Close()
-					handleConnection(conn, bootstrapper)
-				}(peerAddr)
-			}
-
-			checkReceivedPeerInfos(bootstrapper, done)
-		}()
+		// peers are dialed as soon as they are found
+		go findPeerAddrs(numOfPeers, listenAddrs, done, func(peerAddr string) {
+			go dialPeer(peerAddr, bootstrapper)
+		})
 
-		<-done
+		// finding peers and dialing them are also covered by bootstrap timeout
+		if err := bootstrapper.WaitForPeers(); err != nil {
+			exitWithBootstrapError(err)
+		}
+		close(done)
 		err = updateConfigWithPeerInfos(bootstrapper)
 		if err != nil {
 			common.Panic(err)
@@ -116,8 +99,8 @@ func setChannelId() {
 	if err != nil {
 		common.Panic(err)
 	}
-	if len(channelId) != 11 {
-		common.Panic(fmt.Errorf("channelId format is invalid"))
+	if err := common.ValidateChannelId(channelId); err != nil {
+		common.Panic(err)
 	}
 	common.TssCfg.ChannelId = channelId
 }
@@ -128,7 +111,7 @@ func setChannelPasswd() {
 		return
 	}
 
-	if p, err := speakeasy.Ask("> please input password (AGREED offline with peers) of this session:"); err == nil {
+	if p, err := common.ReadSecret(common.TssCfg.ChannelPasswordSource, "please input password (AGREED offline with peers) of this session"); err == nil {
 		if p == "" {
 			common.Panic(fmt.Errorf("channel password should not be empty"))
 		}
@@ -139,29 +122,149 @@ func setChannelPasswd() {
 	}
 }
 
-func findPeerAddrsViaSsdp(n int, listenAddrs string) []string {
+// dialPeer dials peerAddr until it is connected, failures (peer not up yet, unreachable, timeout) are retried with
+// backoff, the process exits with a bootstrap report once bootstrap deadline is passed
+func dialPeer(peerAddr string, bootstrapper *common.Bootstrapper) {
+	dest, err := common.ConvertMultiAddrStrToNormalAddr(peerAddr)
+	if err != nil {
+		common.Panic(fmt.Errorf("failed to convert peer multiAddr to addr: %v", err))
+	}
+	client.Logger.Debugf("going to dial: %s", peerAddr)
+	deadline := bootstrapper.Deadline()
+	dialer := net.Dialer{Deadline: deadline}
+	backoff := dialBackoff
+	conn, err := dialer.Dial("tcp", dest)
+	for conn == nil {
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			exitWithBootstrapError(bootstrapper.TimeoutError())
+		}
+		client.Logger.Debugf("dial %s failed, retry in %v: %v", peerAddr, backoff, err)
+		wait := backoff
+		if !deadline.IsZero() && time.Until(deadline) < wait {
+			wait = time.Until(deadline)
+		}
+		time.Sleep(wait)
+		if backoff *= 2; backoff > maxDialBackoff {
+			backoff = maxDialBackoff
+		}
+		conn, err = dialer.Dial("tcp", dest)
+	}
+	client.Logger.Debugf("done dial: %s", peerAddr)
+	defer conn.Close()
+	handleConnection(conn, bootstrapper)
+}
+
+// findPeerAddrs finds bootstrap addresses of peers and calls found for each of them,
+// they are either pre-filled, from invitation, found on rendezvous server or found via ssdp in LAN
+// done is closed when bootstrapping finished, lan discovery keeps advertising us until then
+func findPeerAddrs(n int, listenAddrs string, done <-chan bool, found func(peerAddr string)) {
+	var peerAddrs []string
 	if common.TssCfg.BMode == common.KeygenMode && len(common.TssCfg.PeerAddrs) == n {
-		return common.TssCfg.PeerAddrs
+		peerAddrs = common.TssCfg.PeerAddrs
+	} else if common.TssCfg.BMode == common.PreRegroupMode && len(common.TssCfg.NewPeerAddrs) == n {
+		peerAddrs = common.TssCfg.NewPeerAddrs
+	} else {
+		// initiator's address comes with invitation, dial it at once rather than waiting it to be discovered
+		if addr := pickConnectableAddr(common.TssCfg.InitiatorAddrs); addr != "" {
+			client.Logger.Debugf("dial initiator from invitation: %s", addr)
+			found(addr)
+		}
+		existingMonikers := make(map[string]struct{})
+		for _, peer := range common.TssCfg.ExpectedPeers {
+			moniker := p2p.GetMonikerFromExpectedPeers(peer)
+			existingMonikers[moniker] = struct{}{}
+		}
+		if common.TssCfg.Rendezvous != "" {
+			findPeerAddrsViaRendezvous(n, listenAddrs, existingMonikers, found)
+			return
+		}
+		findPeerAddrsInLan(n, listenAddrs, existingMonikers, done, found)
+		return
 	}
-	if common.TssCfg.BMode == common.PreRegroupMode && len(common.TssCfg.NewPeerAddrs) == n {
-		return common.TssCfg.NewPeerAddrs
+
+	client.Logger.Debugf("Found peers: %v", peerAddrs)
+	for _, peerAddr := range peerAddrs {
+		found(peerAddr)
 	}
+}
 
-	existingMonikers := make(map[string]struct{})
-	for _, peer := range common.TssCfg.ExpectedPeers {
-		moniker := p2p.GetMonikerFromExpectedPeers(peer)
-		existingMonikers[moniker] = struct{}{}
+// findPeerAddrsInLan finds peers via ssdp or mdns, depends on p2p.discovery
+func findPeerAddrsInLan(n int, listenAddrs string, existingMonikers map[string]struct{}, done <-chan bool, found func(peerAddr string)) {
+	ifaces, err := common.MulticastInterfaces(common.TssCfg.DiscoveryInterface)
+	if err != nil {
+		common.Panic(err)
 	}
-	ssdpSrv := ssdp.NewSsdpService(common.TssCfg.Moniker, common.TssCfg.Vault, listenAddrs, n, existingMonikers)
-	ssdpSrv.CollectPeerAddrs()
-	var peerAddrs []string
-	ssdpSrv.PeerAddrs.Range(func(_, value interface{}) bool {
-		if peerAddr, ok := value.(string); ok {
-			peerAddrs = append(peerAddrs, peerAddr)
+	var discovery common.Discovery
+	switch common.TssCfg.Discovery {
+	case "", common.DiscoverySsdp:
+		discovery = ssdp.NewSsdpService(common.TssCfg.Moniker, common.TssCfg.ChannelId, common.TssCfg.ChannelPassword, listenAddrs, n, existingMonikers, ifaces)
+	case common.DiscoveryMdns:
+		discovery = mdns.NewMdnsService(common.TssCfg.Moniker, common.TssCfg.ChannelId, common.TssCfg.ChannelPassword, listenAddrs, n, existingMonikers, ifaces)
+	default:
+		common.Panic(fmt.Errorf("unknown discovery %s, should be %s or %s", common.TssCfg.Discovery, common.DiscoverySsdp, common.DiscoveryMdns))
+	}
+	go func() {
+		<-done
+		if err := discovery.Close(); err != nil {
+			client.Logger.Debugf("failed to close discovery: %v", err)
 		}
-		return true
-	})
-	return peerAddrs
+	}()
+	if err := discovery.CollectPeerAddrs(found); err != nil {
+		common.Panic(err)
+	}
+}
+
+// findPeerAddrsViaRendezvous registers our listen addresses on rendezvous server and polls until n peers are found
+func findPeerAddrsViaRendezvous(n int, listenAddrs string, existingMonikers map[string]struct{}, found func(peerAddr string)) {
+	rendezvous, err := common.NewRendezvousClient(common.TssCfg.Rendezvous, common.TssCfg.ChannelId, common.TssCfg.ChannelPassword)
+	if err != nil {
+		common.Panic(err)
+	}
+	ourMsg := &common.RendezvousMessage{Moniker: common.TssCfg.Moniker}
+	for _, addr := range strings.Split(listenAddrs, ",") {
+		ourMsg.Addrs = append(ourMsg.Addrs, strings.TrimSpace(addr))
+	}
+
+	peerAddrs := make(map[string]string) // moniker -> addr
+	for {
+		// register in every round so that our message would be kept alive, and server restart is tolerated
+		if err := rendezvous.Register(ourMsg); err != nil {
+			client.Logger.Warning(err)
+		} else if msgs, err := rendezvous.Discover(); err != nil {
+			client.Logger.Warning(err)
+		} else {
+			for _, msg := range msgs {
+				if _, ok := existingMonikers[msg.Moniker]; ok || msg.Moniker == common.TssCfg.Moniker {
+					continue
+				}
+				if _, ok := peerAddrs[msg.Moniker]; ok {
+					continue
+				}
+				if addr := pickConnectableAddr(msg.Addrs); addr != "" {
+					client.Logger.Debugf("found %s (%s) on rendezvous server", msg.Moniker, addr)
+					peerAddrs[msg.Moniker] = addr
+					found(addr)
+				}
+			}
+		}
+		if len(peerAddrs) >= n {
+			break
+		}
+		time.Sleep(rendezvousPollInterval)
+	}
+}
+
+// same rule with ssdp, loopback address is only used when it is the only choice
+func pickConnectableAddr(addrs []string) string {
+	for _, addr := range addrs {
+		if len(addrs) > 1 && common.IsLoopbackAddr(addr) {
+			continue
+		}
+		if _, err := common.ConvertMultiAddrStrToNormalAddr(addr); err == nil {
+			return strings.TrimSpace(addr)
+		}
+	}
+	return ""
 }
 
 func acceptConnRoutine(listener net.Listener, bootstrapper *common.Bootstrapper, done <-chan bool) {
@@ -188,77 +291,44 @@ func acceptConnRoutine(listener net.Listener, bootstrapper *common.Bootstrapper,
 func handleConnection(conn net.Conn, b *common.Bootstrapper) {
 	client.Logger.Debugf("handling connection from %s", conn.RemoteAddr().String())
 
-	sendBootstrapMessage(conn, b.Msg)
-	readBootstrapMessage(conn, b)
-}
-
-func sendBootstrapMessage(conn net.Conn, msg *common.BootstrapMessage) {
-	// TODO: support ipv6
-	realIp := strings.SplitN(conn.LocalAddr().String(), ":", 2)
-	msgForConnect := common.BootstrapMessage{
-		ChannelId: msg.ChannelId,
-		PeerInfo:  msg.PeerInfo,
-		Addr:      common.ReplaceIpInAddr(msg.Addr, realIp[0]),
-	}
-
-	payload, err := proto.Marshal(&msgForConnect)
-	if err != nil {
-		common.SkipTcpClosePanic(fmt.Errorf("bootstrap message cannot be marshaled to protobuf payload: %v", err))
-		return
-	}
-	messageLength := int32(len(payload))
-	err = binary.Write(conn, binary.BigEndian, &messageLength)
+	// peers should connect us via the ip they reached us, rather than the (maybe unspecified) listen ip
+	realIp, _, err := net.SplitHostPort(conn.LocalAddr().String())
 	if err != nil {
-		common.SkipTcpClosePanic(fmt.Errorf("failed to write bootstrap message length: %v", err))
-		return
+		common.Panic(err)
 	}
-	n, err := conn.Write(payload)
-	if int32(n) != messageLength || err != nil {
-		common.SkipTcpClosePanic(fmt.Errorf("failed to write bootstrap message: %v", err))
+	localAddr := common.ReplaceIpInAddr(b.Cfg.ListenAddr, realIp)
+	if err := b.Handshake(conn, localAddr, conn.RemoteAddr().String()); err != nil {
+		if b.IsFinished() {
+			// we are done, the connection is likely a libp2p dial of a peer that finished bootstrap earlier (we share the same port)
+			client.Logger.Debugf("ignore bootstrap connection after bootstrap finished: %v", err)
+		} else if _, ok := err.(*common.BootstrapRejectedError); ok {
+			// peer's channel id or channel password is not correct, we can wait them fix
+			client.Logger.Error(err)
+		} else if _, ok := err.(*common.MalformedBootstrapFrameError); ok {
+			// a peer might finish bootstrap and dial us via libp2p before we finish
+			client.Logger.Debugf("ignore non-bootstrap connection from %s: %v", conn.RemoteAddr().String(), err)
+		} else {
+			common.SkipTcpClosePanic(err)
+		}
 		return
 	}
-	client.Logger.Debugf("sent bootstrap msg: %v to %s", msgForConnect, conn.RemoteAddr().String())
+	client.Logger.Debugf("finished bootstrap handshake with %s", conn.RemoteAddr().String())
 }
 
-func readBootstrapMessage(conn net.Conn, b *common.Bootstrapper) {
-	var messageLength int32
-	err := binary.Read(conn, binary.BigEndian, &messageLength)
-	if err != nil {
-		common.SkipTcpClosePanic(fmt.Errorf("failed to read bootstrap message length: %v", err))
-		return
-	}
-	payload := make([]byte, messageLength)
-	n, err := conn.Read(payload)
-
-	if int32(n) != messageLength {
-		return
-	}
-	if err != nil {
-		common.SkipTcpClosePanic(fmt.Errorf("failed to read bootstrap message: %v", err))
-		return
-	}
-	var peerMsg common.BootstrapMessage
-	err = proto.Unmarshal(payload, &peerMsg)
-	if err != nil {
-		common.SkipTcpClosePanic(fmt.Errorf("failed to unmarshal bootstrap message: %v", err))
-		return
-	}
-	if err := b.HandleBootstrapMsg(peerMsg); err != nil {
-		// peer's channel id or channel password is not correct, we can wait them fix
-		client.Logger.Error(err)
-	}
+// report is more helpful than a stack trace, so we don't use common.Panic here
+func exitWithBootstrapError(err error) {
+	client.Logger.Error(err)
+	os.Exit(1)
 }
 
-func checkReceivedPeerInfos(bootstrapper *common.Bootstrapper, done chan<- bool) {
-	for {
-		if bootstrapper.IsFinished() {
-			done <- true
-			close(done)
-			break
-		} else {
-			time.Sleep(time.Second)
-		}
+// newTssClient creates the client of this command, it blocks until peers are connected and agree on the committee.
+// Like bootstrap errors, what fails it (i.e. a diff of the committee) is more helpful than a stack trace
+func newTssClient(mode client.ClientMode) *client.TssClient {
+	c, err := client.NewTssClient(&common.TssCfg, mode, nil)
+	if err != nil {
+		exitWithBootstrapError(err)
 	}
+	return c
 }
 
 func updateConfigWithPeerInfos(bootstrapper *common.Bootstrapper) error {
@@ -303,22 +373,33 @@ func updateConfigWithPeerInfos(bootstrapper *common.Bootstrapper) error {
 	return nil
 }
 
+// mergeAndUpdate keeps order of existing peers (updating their addresses) and appends newly found ones,
+// so that index-aligned peers and addrs are stable across runs
 func mergeAndUpdate(peerAddrs, expectedPeers, updatedPeerAddrs, updatedPeers []string) ([]string, []string) {
-	mergedPeers := make(map[string]string) // expected peer -> peer addr
-	for i, peer := range expectedPeers {
-		mergedPeers[peer] = peerAddrs[i]
-	}
+	updated := make(map[string]string) // expected peer -> peer addr
 	for i, peer := range updatedPeers {
-		// update addr if already exists
-		mergedPeers[peer] = updatedPeerAddrs[i]
+		updated[peer] = updatedPeerAddrs[i]
 	}
 
-	updatedPeerAddrs = make([]string, 0)
-	updatedPeers = make([]string, 0)
-	for peer, addr := range mergedPeers {
-		updatedPeers = append(updatedPeers, peer)
-		updatedPeerAddrs = append(updatedPeerAddrs, addr)
+	mergedPeerAddrs := make([]string, 0, len(expectedPeers)+len(updatedPeers))
+	mergedPeers := make([]string, 0, len(expectedPeers)+len(updatedPeers))
+	for i, peer := range expectedPeers {
+		addr := peerAddrs[i]
+		if updatedAddr, ok := updated[peer]; ok {
+			// update addr if already exists
+			addr = updatedAddr
+			delete(updated, peer)
+		}
+		mergedPeers = append(mergedPeers, peer)
+		mergedPeerAddrs = append(mergedPeerAddrs, addr)
+	}
+	for _, peer := range updatedPeers {
+		if addr, ok := updated[peer]; ok {
+			mergedPeers = append(mergedPeers, peer)
+			mergedPeerAddrs = append(mergedPeerAddrs, addr)
+			delete(updated, peer)
+		}
 	}
 
-	return updatedPeerAddrs, updatedPeers
+	return mergedPeerAddrs, mergedPeers
 }
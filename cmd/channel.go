@@ -2,12 +2,11 @@ package cmd
 
 import (
 	"bufio"
-	"crypto/rand"
 	"fmt"
-	"math/big"
 	"os"
 	"time"
 
+	"github.com/skip2/go-qrcode"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
@@ -21,15 +20,29 @@ func init() {
 var channelCmd = &cobra.Command{
 	Use:              "channel",
 	Short:            "generate a channel id for bootstrapping",
+	Long:             "generate a channel id for bootstrapping. With --ceremony, an invitation bundling channel id, parameters and addresses is generated as well, which can be passed to keygen, sign or regroup via --invite",
 	TraverseChildren: false, // TODO: figure out how to disable parent's options
 	Run: func(cmd *cobra.Command, args []string) {
-		channelId, err := rand.Int(rand.Reader, big.NewInt(999))
+		expire := askChannelExpire()
+		expireTime := time.Now().Add(time.Duration(expire) * time.Minute).Unix()
+		channelId, err := common.NewChannelId(expireTime)
 		if err != nil {
 			common.Panic(err)
 		}
-		expire := askChannelExpire()
-		expireTime := time.Now().Add(time.Duration(expire) * time.Minute).Unix()
-		fmt.Printf("channel id: %s\n", fmt.Sprintf("%.3d%s", channelId.Int64(), common.ConvertTimestampToHex(expireTime)))
+		fmt.Printf("channel id: %s\n", channelId)
+
+		if ceremony := viper.GetString("ceremony"); ceremony != "" {
+			printInvitation(&common.Invitation{
+				ChannelId:    channelId,
+				Ceremony:     ceremony,
+				Parties:      viper.GetInt("parties"),
+				Threshold:    viper.GetInt("threshold"),
+				NewParties:   viper.GetInt("new_parties"),
+				NewThreshold: viper.GetInt("new_threshold"),
+				Rendezvous:   viper.GetString("p2p.rendezvous"),
+				Addrs:        viper.GetStringSlice("invite_addrs"),
+			})
+		}
 	},
 }
 
@@ -48,3 +61,38 @@ func askChannelExpire() int {
 	}
 	return expire
 }
+
+func printInvitation(invitation *common.Invitation) {
+	invite, err := invitation.Encode()
+	if err != nil {
+		common.Panic(err)
+	}
+	// validate what we are going to share
+	if _, err := common.DecodeInvitation(invite); err != nil {
+		common.Panic(err)
+	}
+	fmt.Printf("invitation (channel password is not included): %s\n", invite)
+	if viper.GetBool("qr") {
+		qr, err := qrcode.New(invite, qrcode.Medium)
+		if err != nil {
+			common.Panic(err)
+		}
+		fmt.Print(qr.ToSmallString(false))
+	}
+}
+
+// applyInvitation fills channel id, parameters and rendezvous server from --invite
+func applyInvitation(ceremony string) {
+	invite := viper.GetString("invite")
+	if invite == "" {
+		return
+	}
+	invitation, err := common.DecodeInvitation(invite)
+	if err != nil {
+		common.Panic(err)
+	}
+	if err := invitation.Apply(&common.TssCfg, ceremony); err != nil {
+		common.Panic(err)
+	}
+	fmt.Printf("joining %s ceremony, channel expires at %s\n", invitation.Ceremony, invitation.Expire().Format(time.RFC3339))
+}
@@ -11,7 +11,6 @@ import (
 	"path"
 	"strings"
 
-	"github.com/bgentry/speakeasy"
 	"github.com/libp2p/go-libp2p-core/crypto"
 	"github.com/phayes/freeport"
 	"github.com/spf13/cobra"
@@ -111,8 +110,18 @@ func setPassphrase() string {
 		return pw
 	}
 
-	if p, err := speakeasy.Ask("> please set password of this vault:"); err == nil {
-		if p2, err := speakeasy.Ask("> please input again:"); err == nil {
+	provider, err := common.NewSecretProvider(viper.GetString("password_source"))
+	if err != nil {
+		common.Panic(err)
+		return ""
+	}
+	if p, err := provider.Secret("please set password of this vault"); err == nil {
+		if !provider.Interactive() {
+			checkComplexityOfPassword(p)
+			viper.Set("password", p)
+			return p
+		}
+		if p2, err := provider.Secret("please input again"); err == nil {
 			if p2 != p {
 				common.Panic(fmt.Errorf("two inputs does not match, please start again"))
 				return ""
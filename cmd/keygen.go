@@ -12,7 +12,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/bgentry/speakeasy"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/tendermint/tendermint/crypto"
@@ -40,13 +39,19 @@ var keygenCmd = &cobra.Command{
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		checkOverride()
+		applyInvitation(common.InviteCeremonyKeygen)
+		useManifest := loadManifestIfNeeded(common.KeygenMode)
 		setN()
 		setT()
-		bootstrapCmd.Run(cmd, args)
+		if !useManifest {
+			bootstrapCmd.Run(cmd, args)
+		}
 		checkN()
 		setPassphrase()
-		c := client.NewTssClient(&common.TssCfg, client.KeygenMode, false)
-		c.Start()
+		c := newTssClient(client.KeygenMode)
+		if err := c.Start(); err != nil {
+			common.Panic(err)
+		}
 
 		updateConfig()
 		addToBnbcli(c.PubKey())
@@ -119,7 +124,7 @@ func askPassphrase() string {
 		return pw
 	}
 
-	if p, err := speakeasy.Ask("> Password to sign with this vault:"); err == nil {
+	if p, err := common.ReadSecret(viper.GetString("password_source"), "Password to sign with this vault"); err == nil {
 		viper.Set("password", p)
 		checkComplexityOfPassword(p)
 		return p
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/bnb-chain/tss/client"
+	"github.com/bnb-chain/tss/common"
+)
+
+func init() {
+	manifestCmd.AddCommand(manifestCreateCmd)
+	manifestCmd.AddCommand(manifestSignCmd)
+	manifestCmd.AddCommand(manifestImportCmd)
+	rootCmd.AddCommand(manifestCmd)
+}
+
+var manifestCmd = &cobra.Command{
+	Use:   "manifest",
+	Short: "manage committee manifest agreed offline",
+	Long:  "a committee manifest lists moniker, libp2p id, addresses and role of every party and t/n of the scheme. Once it is signed by all parties and imported, keygen, sign and regroup can start with --manifest without ssdp, channel id and channel password",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlags(cmd.Flags())
+		vault := askVault()
+		passphrase := askPassphrase()
+		if err := common.ReadConfigFromHome(viper.GetViper(), false, viper.GetString(flagHome), vault, passphrase); err != nil {
+			common.Panic(err)
+		}
+		initLogLevel(common.TssCfg)
+	},
+}
+
+var manifestCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "create a manifest or add this party into an existing one",
+	Long:  "create a manifest file with this party as a member, if the file already exists this party is added (or updated). Pass the file to every party to add themselves, then let them sign it",
+	Run: func(cmd *cobra.Command, args []string) {
+		file := manifestFile()
+		m, err := common.LoadManifest(file)
+		if os.IsNotExist(err) {
+			setChannelId()
+			m = common.NewManifest(common.TssCfg.ChannelId, common.TssCfg.Threshold, common.TssCfg.Parties, common.TssCfg.NewThreshold, common.TssCfg.NewParties)
+		} else if err != nil {
+			common.Panic(err)
+		} else {
+			if cmd.Flags().Changed("channel_id") && common.TssCfg.ChannelId != m.ChannelId {
+				common.Panic(fmt.Errorf("channel id of existing manifest is %s, rather than %s", m.ChannelId, common.TssCfg.ChannelId))
+			}
+			checkManifestParam(cmd, "threshold", m.Threshold)
+			checkManifestParam(cmd, "parties", m.Parties)
+			checkManifestParam(cmd, "new_threshold", m.NewThreshold)
+			checkManifestParam(cmd, "new_parties", m.NewParties)
+		}
+
+		addrs := viper.GetStringSlice("addrs")
+		if len(addrs) == 0 {
+			addrs = []string{common.TssCfg.ListenAddr}
+		}
+		for _, addr := range addrs {
+			if common.IsUnspecifiedAddr(addr) {
+				client.Logger.Warningf("%s is not connectable by peers, please set --addrs", addr)
+			}
+		}
+		if err := m.AddMember(common.ManifestMember{
+			Moniker: common.TssCfg.Moniker,
+			Id:      string(common.TssCfg.Id),
+			Addrs:   addrs,
+			Role:    viper.GetString("role"),
+		}); err != nil {
+			common.Panic(err)
+		}
+		if err := m.Save(file); err != nil {
+			common.Panic(err)
+		}
+		client.Logger.Infof("%s is added into %s, %d member(s) in total, all signatures have been cleared", common.TssCfg.Moniker, file, len(m.Members))
+	},
+}
+
+var manifestSignCmd = &cobra.Command{
+	Use:   "sign",
+	Short: "sign a manifest with node key of this party",
+	Long:  "sign a manifest with node key of this party, please check the content (especially t/n, ids and roles) before signing",
+	Run: func(cmd *cobra.Command, args []string) {
+		file := manifestFile()
+		m, err := common.LoadManifest(file)
+		if err != nil {
+			common.Panic(err)
+		}
+		nodeKey, err := common.LoadNodeKey(common.TssCfg.Home, common.TssCfg.Vault)
+		if err != nil {
+			common.Panic(err)
+		}
+		if err := m.Sign(nodeKey); err != nil {
+			common.Panic(err)
+		}
+		if err := m.Save(file); err != nil {
+			common.Panic(err)
+		}
+		client.Logger.Infof("%s signed %s, %d of %d member(s) have signed", common.TssCfg.Moniker, file, len(m.Signatures), len(m.Members))
+	},
+}
+
+var manifestImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "verify a manifest signed by all members and import it into vault",
+	Long:  "verify a manifest signed by all members and import it into vault, keygen, sign and regroup with --manifest would start from the imported one",
+	Run: func(cmd *cobra.Command, args []string) {
+		m, err := common.LoadManifest(manifestFile())
+		if err != nil {
+			common.Panic(err)
+		}
+		if _, ok := m.Member(string(common.TssCfg.Id)); !ok {
+			common.Panic(fmt.Errorf("this party (%s) is not a member of the manifest", common.TssCfg.Id))
+		}
+		if err := common.ImportManifest(common.TssCfg.Home, common.TssCfg.Vault, m); err != nil {
+			common.Panic(err)
+		}
+		client.Logger.Infof("manifest of %d member(s) has been imported", len(m.Members))
+	},
+}
+
+func manifestFile() string {
+	file := viper.GetString("file")
+	if file == "" {
+		common.Panic(fmt.Errorf("please set path of manifest via --file"))
+	}
+	return file
+}
+
+func checkManifestParam(cmd *cobra.Command, flag string, inManifest int) {
+	if cmd.Flags().Changed(flag) && viper.GetInt(flag) != inManifest {
+		common.Panic(fmt.Errorf("%s of existing manifest is %d, rather than %d", flag, inManifest, viper.GetInt(flag)))
+	}
+}
+
+// loadManifestIfNeeded applies imported manifest to config if --manifest is set, returns whether it is applied
+func loadManifestIfNeeded(mode common.BootstrapMode) bool {
+	if !common.TssCfg.UseManifest {
+		return false
+	}
+	m, err := common.LoadImportedManifest(common.TssCfg.Home, common.TssCfg.Vault)
+	if err != nil {
+		common.Panic(err)
+	}
+	if err := m.Apply(&common.TssCfg, mode); err != nil {
+		common.Panic(err)
+	}
+	client.Logger.Infof("start from imported manifest of %d member(s)", len(m.Members))
+	return true
+}
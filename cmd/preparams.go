@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/bnb-chain/tss-lib/v2/ecdsa/keygen"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/bnb-chain/tss/client"
+	"github.com/bnb-chain/tss/common"
+)
+
+const (
+	preParamsGenTimeout    = 30 * time.Minute
+	preParamsCheckInterval = 10 * time.Second
+)
+
+func init() {
+	rootCmd.AddCommand(preParamsCmd)
+}
+
+var preParamsCmd = &cobra.Command{
+	Use:   "preparams",
+	Short: "pre-generate paillier keys and safe primes for keygen",
+	Long:  "generate paillier keys and safe primes ahead of time and store them encrypted in vault, so that keygen and regroup (as new committee) can start immediately",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		vault := askVault()
+		passphrase := askPassphrase()
+		if err := common.ReadConfigFromHome(viper.GetViper(), false, viper.GetString(flagHome), vault, passphrase); err != nil {
+			common.Panic(err)
+		}
+		initLogLevel(common.TssCfg)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		poolSize := viper.GetInt("pool_size")
+		if poolSize <= 0 {
+			poolSize = 1
+		}
+
+		for {
+			for common.NumOfPreParams(common.TssCfg.Home, common.TssCfg.Vault) < poolSize {
+				generatePreParams()
+			}
+			if !viper.GetBool("daemon") {
+				break
+			}
+			// keygen or regroup might have consumed pre params, check whether we need refill the pool
+			time.Sleep(preParamsCheckInterval)
+		}
+		client.Logger.Infof("%d pre params are available in vault", common.NumOfPreParams(common.TssCfg.Home, common.TssCfg.Vault))
+	},
+}
+
+func generatePreParams() {
+	client.Logger.Info("generating pre params, this might take several minutes...")
+	start := time.Now()
+	preParams, err := keygen.GeneratePreParams(preParamsGenTimeout)
+	if err != nil {
+		// timeout is not fatal, it just means we were unlucky on finding safe primes
+		client.Logger.Warningf("failed to generate pre params, will retry: %v", err)
+		return
+	}
+	if err := common.SavePreParams(common.TssCfg.Home, common.TssCfg.Vault, preParams, common.TssCfg.KDFConfig, common.TssCfg.Password); err != nil {
+		common.Panic(err)
+	}
+	client.Logger.Infof("generated pre params in %v", time.Since(start))
+}
@@ -39,7 +39,19 @@ var regroupCmd = &cobra.Command{
 			mustNew = true
 		}
 
-		if !mustNew {
+		applyInvitation(common.InviteCeremonyRegroup)
+		// manifest decides whether we are old or new committee, an old party staying in new committee
+		// should be listed twice in manifest: its current vault as old and a newly initialized vault as new
+		useManifest := loadManifestIfNeeded(common.RegroupMode)
+		if useManifest && mustNew && common.TssCfg.IsOldCommittee {
+			common.Panic(fmt.Errorf("this vault has no secret share, it cannot be an old party in manifest"))
+		}
+
+		if useManifest {
+			if mustNew {
+				setPassphrase()
+			}
+		} else if !mustNew {
 			setIsOld()
 			setIsNew()
 		} else {
@@ -78,14 +90,17 @@ var regroupCmd = &cobra.Command{
 				"--home", common.TssCfg.Home,
 				"--vault_name", tmpVault,
 				"--moniker", tmpMoniker,
-				"--password", common.TssCfg.Password,
+				"--password_source", childSecretSource,
 				"--p2p.listen", common.TssCfg.NewListenAddr)
 			tssInit.Stdin = devnull
 			tssInit.Stdout = devnull
+			initSecrets := newSecretPipe(common.TssCfg.Password)
+			tssInit.ExtraFiles = []*os.File{initSecrets}
 
 			if err := tssInit.Run(); err != nil {
 				common.Panic(fmt.Errorf("failed to fork tss init command: %v", err))
 			}
+			initSecrets.Close()
 
 			setChannelId()
 			setChannelPasswd()
@@ -95,15 +110,22 @@ var regroupCmd = &cobra.Command{
 				"--home",
 				common.TssCfg.Home,
 				"--vault_name", tmpVault,
-				"--password", common.TssCfg.Password,
+				"--password_source", childSecretSource,
 				"--parties", strconv.Itoa(common.TssCfg.Parties),
 				"--threshold", strconv.Itoa(common.TssCfg.Threshold),
 				"--new_parties", strconv.Itoa(common.TssCfg.NewParties),
 				"--new_threshold", strconv.Itoa(common.TssCfg.NewThreshold),
-				"--channel_password", common.TssCfg.ChannelPassword,
+				"--channel_password_source", childSecretSource,
 				"--channel_id", common.TssCfg.ChannelId,
 				"--p2p.broadcast_sanity_check", strconv.FormatBool(common.TssCfg.BroadcastSanityCheck),
+				"--p2p.broadcast_mode", common.TssCfg.BroadcastMode,
+				"--p2p.max_message_size", strconv.Itoa(common.TssCfg.MaxMessageSize),
 				"--p2p.new_peer_addrs", strings.Join(common.TssCfg.NewPeerAddrs, ","),
+				"--p2p.rendezvous", common.TssCfg.Rendezvous,
+				"--p2p.discovery", common.TssCfg.Discovery,
+				"--p2p.discovery_interface", common.TssCfg.DiscoveryInterface,
+				"--invite", viper.GetString("invite"),
+				"--bootstrap_timeout", common.TssCfg.BootstrapTimeout.String(),
 				"--pubkey", client.PubKeyCompressedHexString(),
 				"--log_level", common.TssCfg.LogLevel)
 			stdOut, err := os.Create(path.Join(common.TssCfg.Home, tmpVault, "tss.log"))
@@ -113,18 +135,26 @@ var regroupCmd = &cobra.Command{
 			tssRegroup.Stdin = devnull
 			tssRegroup.Stdout = stdOut
 			tssRegroup.Stderr = stdOut
+			// child reads vault password first (PreRun) and then channel password (bootstrap)
+			regroupSecrets := newSecretPipe(common.TssCfg.Password, common.TssCfg.ChannelPassword)
+			tssRegroup.ExtraFiles = []*os.File{regroupSecrets}
 
 			if err := tssRegroup.Start(); err != nil {
 				common.Panic(fmt.Errorf("failed to fork tss regroup command: %v", err))
 			}
+			regroupSecrets.Close()
 		}
 
-		common.TssCfg.BMode = common.PreRegroupMode
-		bootstrapCmd.Run(cmd, args)
+		if !useManifest {
+			common.TssCfg.BMode = common.PreRegroupMode
+			bootstrapCmd.Run(cmd, args)
+		}
 		common.TssCfg.BMode = common.RegroupMode
 
-		c := client.NewTssClient(&common.TssCfg, client.RegroupMode, false)
-		c.Start()
+		c := newTssClient(client.RegroupMode)
+		if err := c.Start(); err != nil {
+			common.Panic(err)
+		}
 
 		if !common.TssCfg.IsOldCommittee {
 			// delete tmp regroup suffix
@@ -187,6 +217,25 @@ var regroupCmd = &cobra.Command{
 	},
 }
 
+// the first entry of exec.Cmd.ExtraFiles becomes file descriptor 3 of child process
+const childSecretSource = "fd:3"
+
+// newSecretPipe writes secrets line by line into a pipe and returns its read end,
+// which should be handed to child process via ExtraFiles so that secrets never appear in its arguments
+func newSecretPipe(secrets ...string) *os.File {
+	r, w, err := os.Pipe()
+	if err != nil {
+		common.Panic(err)
+	}
+	defer w.Close()
+	for _, secret := range secrets {
+		if _, err := fmt.Fprintln(w, secret); err != nil {
+			common.Panic(fmt.Errorf("failed to pass secret to child process: %v", err))
+		}
+	}
+	return r
+}
+
 func setIsOld() {
 	if common.TssCfg.IsOldCommittee {
 		return
@@ -61,6 +61,15 @@ func bindP2pConfigs() {
 	//rootCmd.PersistentFlags().StringSlice("p2p.relays", []string{}, "relay server list")
 	keygenCmd.PersistentFlags().StringSlice("p2p.peer_addrs", []string{}, "peer's multiple addresses")
 	regroupCmd.PersistentFlags().StringSlice("p2p.new_peer_addrs", []string{}, "unknown peer's multiple addresses")
+	initCmd.PersistentFlags().String("p2p.rendezvous", "", "url of rendezvous server to find peers not in the same LAN, i.e. http://1.2.3.4:27149")
+	keygenCmd.PersistentFlags().String("p2p.rendezvous", "", "url of rendezvous server to find peers not in the same LAN, overrides the one in config")
+	regroupCmd.PersistentFlags().String("p2p.rendezvous", "", "url of rendezvous server to find peers not in the same LAN, overrides the one in config")
+	for _, cmd := range []*cobra.Command{initCmd, keygenCmd, regroupCmd} {
+		cmd.PersistentFlags().String("p2p.discovery", "", "how to find peers in the same LAN: ssdp (default) or mdns")
+		cmd.PersistentFlags().String("p2p.discovery_interface", "", "network interface used to find peers in the same LAN, i.e. eth0, all multicast capable ones by default")
+	}
+	serverCmd.PersistentFlags().String("p2p.listen", "", "libp2p listen multiaddress of bootstrap and relay server, disabled if empty")
+	serverCmd.PersistentFlags().String("rendezvous_listen", "", "host:port that rendezvous service listens on, disabled if empty")
 	//rootCmd.PersistentFlags().StringSlice("p2p.peers", []string{}, "peers in this threshold scheme")
 	//rootCmd.PersistentFlags().Bool("p2p.default_bootstrap", false, "whether to use default bootstrap")
 }
@@ -89,13 +98,23 @@ func bindClientConfigs() {
 	regroupCmd.PersistentFlags().Int("new_threshold", 0, "new threshold of regrouped scheme")
 	regroupCmd.PersistentFlags().Int("new_parties", 0, "new total parties of regrouped scheme")
 	rootCmd.PersistentFlags().String("password", "", "password, should only be used for testing. If empty, you will be prompted for password to save/load the secret/public share and config")
+	rootCmd.PersistentFlags().String("password_source", "", "where to read password from if --password is empty: prompt, file:<path>, fd:<n>, env:<name> or askpass:<program>")
 	signCmd.PersistentFlags().String("message", "", "message(in *big.Int.String() format) to be signed, only used in sign mode")
+	signCmd.PersistentFlags().StringSlice("signers", []string{}, "monikers (including this party) chosen to sign, other parties are rejected. If empty, signers are whoever online first")
 	rootCmd.PersistentFlags().String("log_level", "info", "log level")
 
 	keygenCmd.PersistentFlags().Bool("p2p.broadcast_sanity_check", true, "whether verify broadcast message's hash with peers")
 	signCmd.PersistentFlags().Bool("p2p.broadcast_sanity_check", true, "whether verify broadcast message's hash with peers")
 	regroupCmd.PersistentFlags().Bool("p2p.broadcast_sanity_check", true, "whether verify broadcast message's hash with peers")
 
+	keygenCmd.PersistentFlags().String("p2p.broadcast_mode", "", "how broadcast messages are checked: sanity_check (verify hash with peers, default unless --p2p.broadcast_sanity_check=false), reliable (echo/ready reliable broadcast) or none")
+	signCmd.PersistentFlags().String("p2p.broadcast_mode", "", "how broadcast messages are checked: sanity_check (verify hash with peers, default unless --p2p.broadcast_sanity_check=false), reliable (echo/ready reliable broadcast) or none")
+	regroupCmd.PersistentFlags().String("p2p.broadcast_mode", "", "how broadcast messages are checked: sanity_check (verify hash with peers, default unless --p2p.broadcast_sanity_check=false), reliable (echo/ready reliable broadcast) or none")
+
+	keygenCmd.PersistentFlags().Int("p2p.max_message_size", common.DefaultMaxFrameSize, "maximum size in bytes of a message from peers, larger ones are rejected")
+	signCmd.PersistentFlags().Int("p2p.max_message_size", common.DefaultMaxFrameSize, "maximum size in bytes of a message from peers, larger ones are rejected")
+	regroupCmd.PersistentFlags().Int("p2p.max_message_size", common.DefaultMaxFrameSize, "maximum size in bytes of a message from peers, larger ones are rejected")
+
 	keygenCmd.PersistentFlags().String("channel_id", "", "channel id of this session")
 	signCmd.PersistentFlags().String("channel_id", "", "channel id of this session")
 	regroupCmd.PersistentFlags().String("channel_id", "", "channel id of this session")
@@ -104,7 +123,43 @@ func bindClientConfigs() {
 	signCmd.PersistentFlags().String("channel_password", "", "channel password of this session")
 	regroupCmd.PersistentFlags().String("channel_password", "", "channel password of this session")
 
+	keygenCmd.PersistentFlags().String("channel_password_source", "", "where to read channel password from if --channel_password is empty: prompt, file:<path>, fd:<n>, env:<name> or askpass:<program>")
+	signCmd.PersistentFlags().String("channel_password_source", "", "where to read channel password from if --channel_password is empty: prompt, file:<path>, fd:<n>, env:<name> or askpass:<program>")
+	regroupCmd.PersistentFlags().String("channel_password_source", "", "where to read channel password from if --channel_password is empty: prompt, file:<path>, fd:<n>, env:<name> or askpass:<program>")
+
+	keygenCmd.PersistentFlags().Duration("bootstrap_timeout", 0, "how long to wait for peers before exiting with a report of found, missing and mismatched peers, i.e. 10m. 0 means wait forever")
+	signCmd.PersistentFlags().Duration("bootstrap_timeout", 0, "how long to wait for peers before exiting with a report of found, missing and mismatched peers, i.e. 10m. 0 means wait forever")
+	regroupCmd.PersistentFlags().Duration("bootstrap_timeout", 0, "how long to wait for peers before exiting with a report of found, missing and mismatched peers, i.e. 10m. 0 means wait forever")
+
+	keygenCmd.PersistentFlags().Bool("manifest", false, "start from manifest imported by `tss manifest import`, ssdp and channel id/password are not needed")
+	signCmd.PersistentFlags().Bool("manifest", false, "start from manifest imported by `tss manifest import`, ssdp and channel id/password are not needed")
+	regroupCmd.PersistentFlags().Bool("manifest", false, "start from manifest imported by `tss manifest import`, ssdp and channel id/password are not needed")
+
+	manifestCmd.PersistentFlags().String("file", "", "path to manifest file")
+	manifestCreateCmd.Flags().String("channel_id", "", "channel id (generated by `tss channel`) the manifest is bound to, it expires with the channel id. Only used when creating a new manifest")
+	manifestCreateCmd.Flags().Int("threshold", 0, "threshold of the scheme, only used when creating a new manifest")
+	manifestCreateCmd.Flags().Int("parties", 0, "total parties of the scheme, only used when creating a new manifest")
+	manifestCreateCmd.Flags().Int("new_threshold", 0, "new threshold of regroup, only used when creating a new manifest")
+	manifestCreateCmd.Flags().Int("new_parties", 0, "new total parties of regroup, only used when creating a new manifest")
+	manifestCreateCmd.Flags().String("role", common.ManifestRoleMember, "role of this party: member (keygen), signer (sign), old or new (regroup)")
+	manifestCreateCmd.Flags().StringSlice("addrs", []string{}, "multiaddrs this party can be connected by peers, default to listen address")
+
 	channelCmd.PersistentFlags().Int("channel_expire", 0, "expire time in minutes of this channel")
+	channelCmd.PersistentFlags().String("ceremony", "", "generate an invitation for this ceremony as well: keygen, sign or regroup")
+	channelCmd.PersistentFlags().Int("parties", 0, "total parties put into invitation")
+	channelCmd.PersistentFlags().Int("threshold", 0, "threshold put into invitation")
+	channelCmd.PersistentFlags().Int("new_parties", 0, "new total parties put into invitation (regroup)")
+	channelCmd.PersistentFlags().Int("new_threshold", 0, "new threshold put into invitation (regroup)")
+	channelCmd.PersistentFlags().String("p2p.rendezvous", "", "rendezvous server put into invitation")
+	channelCmd.PersistentFlags().StringSlice("invite_addrs", []string{}, "bootstrap multiaddrs of initiator put into invitation")
+	channelCmd.PersistentFlags().Bool("qr", false, "render invitation as QR code in terminal")
+
+	keygenCmd.PersistentFlags().String("invite", "", "invitation generated by `tss channel --ceremony keygen`, fills in everything except channel password")
+	signCmd.PersistentFlags().String("invite", "", "invitation generated by `tss channel --ceremony sign`, fills in everything except channel password")
+	regroupCmd.PersistentFlags().String("invite", "", "invitation generated by `tss channel --ceremony regroup`, fills in everything except channel password")
+
+	preParamsCmd.PersistentFlags().Int("pool_size", 1, "how many pre params should be kept in vault")
+	preParamsCmd.PersistentFlags().Bool("daemon", false, "keep running and refill the pool once pre params are consumed by keygen or regroup")
 
 	regroupCmd.PersistentFlags().Bool("is_old", false, "whether this party is an old committee. If it is set to true, it will participant signing in regroup. There should be only t+1 parties set this to true for one regroup")
 	regroupCmd.PersistentFlags().Bool("is_new_member", false, "whether this party is new committee, for new party it will changed to true automatically. if an old party set this to true, its share will be replaced by one generated one")
@@ -118,6 +173,7 @@ func initLogLevel(cfg common.TssConfig) {
 	log.SetLogLevel("trans", cfg.LogLevel)
 	log.SetLogLevel("p2p_utils", cfg.LogLevel)
 	log.SetLogLevel("common", cfg.LogLevel)
+	log.SetLogLevel("security", cfg.LogLevel)
 
 	// libp2p loggers
 	log.SetLogLevel("dht", "error")
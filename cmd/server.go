@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	"github.com/bnb-chain/tss/common"
 	"github.com/bnb-chain/tss/server"
@@ -14,10 +17,27 @@ func init() {
 var serverCmd = &cobra.Command{
 	Use:    "server",
 	Short:  "bootstrap and relay server helps node (dynamic ip) discovery and NAT traversal",
-	Long:   "bootstrap and relay server helps node (dynamic ip) discovery and NAT traversal",
+	Long:   "bootstrap and relay server helps node (dynamic ip) discovery and NAT traversal. With --rendezvous_listen, it also serves as a rendezvous point for parties not in the same LAN",
 	Hidden: true,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// server doesn't own a vault, configs are only from command line
+		common.TssCfg.Home = viper.GetString(flagHome)
+		common.TssCfg.ListenAddr = viper.GetString("p2p.listen")
+		common.TssCfg.LogLevel = viper.GetString("log_level")
+		initLogLevel(common.TssCfg)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		server.NewTssBootstrapServer(common.TssCfg.Home, common.TssCfg.P2PConfig)
+		rendezvousListen := viper.GetString("rendezvous_listen")
+		if common.TssCfg.ListenAddr == "" && rendezvousListen == "" {
+			common.Panic(fmt.Errorf("at least one of --p2p.listen and --rendezvous_listen should be set"))
+		}
+
+		if common.TssCfg.ListenAddr != "" {
+			server.NewTssBootstrapServer(common.TssCfg.Home, common.TssCfg.P2PConfig)
+		}
+		if rendezvousListen != "" {
+			common.Panic(server.NewRendezvousServer().ListenAndServe(rendezvousListen))
+		}
 		select {}
 	},
 }
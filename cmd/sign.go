@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"github.com/bnb-chain/tss/client"
 	"github.com/bnb-chain/tss/common"
+	"github.com/bnb-chain/tss/p2p"
 )
 
 func init() {
@@ -25,15 +28,50 @@ var signCmd = &cobra.Command{
 		initLogLevel(common.TssCfg)
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		setChannelId()
-		setChannelPasswd()
+		applyInvitation(common.InviteCeremonySign)
+		if !loadManifestIfNeeded(common.SignMode) {
+			setChannelId()
+			setChannelPasswd()
+		} else if len(common.TssCfg.Signers) > 0 {
+			common.Panic(fmt.Errorf("--signers cannot be used with --manifest, signers are members with role %s in manifest", common.ManifestRoleSigner))
+		}
+		checkSigners()
 		setMessage()
 
-		c := client.NewTssClient(&common.TssCfg, client.SignMode, false)
-		c.Start()
+		c := newTssClient(client.SignMode)
+		if err := c.Start(); err != nil {
+			common.Panic(err)
+		}
 	},
 }
 
+// checkSigners validates signers chosen by --signers are known parties of this vault, including ourselves
+func checkSigners() {
+	if len(common.TssCfg.Signers) == 0 {
+		return
+	}
+	known := map[string]struct{}{common.TssCfg.Moniker: {}}
+	for _, peer := range common.TssCfg.ExpectedPeers {
+		known[p2p.GetMonikerFromExpectedPeers(peer)] = struct{}{}
+	}
+	chosen := make(map[string]struct{})
+	for _, signer := range common.TssCfg.Signers {
+		if _, ok := known[signer]; !ok {
+			common.Panic(fmt.Errorf("signer %s is not a party of this vault", signer))
+		}
+		if _, ok := chosen[signer]; ok {
+			common.Panic(fmt.Errorf("signer %s is chosen more than once", signer))
+		}
+		chosen[signer] = struct{}{}
+	}
+	if _, ok := chosen[common.TssCfg.Moniker]; !ok {
+		common.Panic(fmt.Errorf("this party (%s) is not chosen by --signers", common.TssCfg.Moniker))
+	}
+	if len(chosen) < common.TssCfg.Threshold+1 {
+		common.Panic(fmt.Errorf("no enough signers (%d) to meet requirement: %d", len(chosen), common.TssCfg.Threshold+1))
+	}
+}
+
 // TODO: use MessageBridge
 func setMessage() {
 	common.TssCfg.Message = "0"
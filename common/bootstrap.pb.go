@@ -20,18 +20,17 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-type BootstrapMessage struct {
+type BootstrapHello struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
 	ChannelId string `protobuf:"bytes,1,opt,name=ChannelId,proto3" json:"ChannelId,omitempty"`
-	PeerInfo  []byte `protobuf:"bytes,2,opt,name=PeerInfo,proto3" json:"PeerInfo,omitempty"`
-	Addr      string `protobuf:"bytes,3,opt,name=addr,proto3" json:"addr,omitempty"`
+	Share     []byte `protobuf:"bytes,2,opt,name=Share,proto3" json:"Share,omitempty"` // pake share, see pake.go
 }
 
-func (x *BootstrapMessage) Reset() {
-	*x = BootstrapMessage{}
+func (x *BootstrapHello) Reset() {
+	*x = BootstrapHello{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_bootstrap_proto_msgTypes[0]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -39,13 +38,13 @@ func (x *BootstrapMessage) Reset() {
 	}
 }
 
-func (x *BootstrapMessage) String() string {
+func (x *BootstrapHello) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*BootstrapMessage) ProtoMessage() {}
+func (*BootstrapHello) ProtoMessage() {}
 
-func (x *BootstrapMessage) ProtoReflect() protoreflect.Message {
+func (x *BootstrapHello) ProtoReflect() protoreflect.Message {
 	mi := &file_bootstrap_proto_msgTypes[0]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -57,44 +56,163 @@ func (x *BootstrapMessage) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use BootstrapMessage.ProtoReflect.Descriptor instead.
-func (*BootstrapMessage) Descriptor() ([]byte, []int) {
+// Deprecated: Use BootstrapHello.ProtoReflect.Descriptor instead.
+func (*BootstrapHello) Descriptor() ([]byte, []int) {
 	return file_bootstrap_proto_rawDescGZIP(), []int{0}
 }
 
-func (x *BootstrapMessage) GetChannelId() string {
+func (x *BootstrapHello) GetChannelId() string {
 	if x != nil {
 		return x.ChannelId
 	}
 	return ""
 }
 
-func (x *BootstrapMessage) GetPeerInfo() []byte {
+func (x *BootstrapHello) GetShare() []byte {
+	if x != nil {
+		return x.Share
+	}
+	return nil
+}
+
+type BootstrapConfirm struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Tag      []byte `protobuf:"bytes,1,opt,name=Tag,proto3" json:"Tag,omitempty"`           // proves that we derived the same session key, i.e. we know the same channel password
+	PeerInfo []byte `protobuf:"bytes,2,opt,name=PeerInfo,proto3" json:"PeerInfo,omitempty"` // PeerParam encrypted by session key
+	Addr     string `protobuf:"bytes,3,opt,name=addr,proto3" json:"addr,omitempty"`
+}
+
+func (x *BootstrapConfirm) Reset() {
+	*x = BootstrapConfirm{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bootstrap_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BootstrapConfirm) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BootstrapConfirm) ProtoMessage() {}
+
+func (x *BootstrapConfirm) ProtoReflect() protoreflect.Message {
+	mi := &file_bootstrap_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BootstrapConfirm.ProtoReflect.Descriptor instead.
+func (*BootstrapConfirm) Descriptor() ([]byte, []int) {
+	return file_bootstrap_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BootstrapConfirm) GetTag() []byte {
+	if x != nil {
+		return x.Tag
+	}
+	return nil
+}
+
+func (x *BootstrapConfirm) GetPeerInfo() []byte {
 	if x != nil {
 		return x.PeerInfo
 	}
 	return nil
 }
 
-func (x *BootstrapMessage) GetAddr() string {
+func (x *BootstrapConfirm) GetAddr() string {
 	if x != nil {
 		return x.Addr
 	}
 	return ""
 }
 
+type BootstrapResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Accepted bool   `protobuf:"varint,1,opt,name=Accepted,proto3" json:"Accepted,omitempty"`
+	Reason   string `protobuf:"bytes,2,opt,name=Reason,proto3" json:"Reason,omitempty"` // why we rejected the peer
+}
+
+func (x *BootstrapResult) Reset() {
+	*x = BootstrapResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bootstrap_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BootstrapResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BootstrapResult) ProtoMessage() {}
+
+func (x *BootstrapResult) ProtoReflect() protoreflect.Message {
+	mi := &file_bootstrap_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BootstrapResult.ProtoReflect.Descriptor instead.
+func (*BootstrapResult) Descriptor() ([]byte, []int) {
+	return file_bootstrap_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BootstrapResult) GetAccepted() bool {
+	if x != nil {
+		return x.Accepted
+	}
+	return false
+}
+
+func (x *BootstrapResult) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
 var File_bootstrap_proto protoreflect.FileDescriptor
 
 var file_bootstrap_proto_rawDesc = []byte{
 	0x0a, 0x0f, 0x62, 0x6f, 0x6f, 0x74, 0x73, 0x74, 0x72, 0x61, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x12, 0x06, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x22, 0x60, 0x0a, 0x10, 0x42, 0x6f, 0x6f,
-	0x74, 0x73, 0x74, 0x72, 0x61, 0x70, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x1c, 0x0a,
-	0x09, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x09, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x49, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x50,
-	0x65, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x50,
-	0x65, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x61, 0x64, 0x64, 0x72, 0x18,
-	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x61, 0x64, 0x64, 0x72, 0x42, 0x09, 0x5a, 0x07, 0x2f,
-	0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x6f, 0x12, 0x06, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x22, 0x44, 0x0a, 0x0e, 0x42, 0x6f, 0x6f,
+	0x74, 0x73, 0x74, 0x72, 0x61, 0x70, 0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x12, 0x1c, 0x0a, 0x09, 0x43,
+	0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09,
+	0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x49, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x53, 0x68, 0x61,
+	0x72, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x53, 0x68, 0x61, 0x72, 0x65, 0x22,
+	0x54, 0x0a, 0x10, 0x42, 0x6f, 0x6f, 0x74, 0x73, 0x74, 0x72, 0x61, 0x70, 0x43, 0x6f, 0x6e, 0x66,
+	0x69, 0x72, 0x6d, 0x12, 0x10, 0x0a, 0x03, 0x54, 0x61, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x03, 0x54, 0x61, 0x67, 0x12, 0x1a, 0x0a, 0x08, 0x50, 0x65, 0x65, 0x72, 0x49, 0x6e, 0x66,
+	0x6f, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x50, 0x65, 0x65, 0x72, 0x49, 0x6e, 0x66,
+	0x6f, 0x12, 0x12, 0x0a, 0x04, 0x61, 0x64, 0x64, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x61, 0x64, 0x64, 0x72, 0x22, 0x45, 0x0a, 0x0f, 0x42, 0x6f, 0x6f, 0x74, 0x73, 0x74, 0x72,
+	0x61, 0x70, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x41, 0x63, 0x63, 0x65,
+	0x70, 0x74, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x41, 0x63, 0x63, 0x65,
+	0x70, 0x74, 0x65, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x42, 0x09, 0x5a, 0x07,
+	0x2f, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -109,9 +227,11 @@ func file_bootstrap_proto_rawDescGZIP() []byte {
 	return file_bootstrap_proto_rawDescData
 }
 
-var file_bootstrap_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_bootstrap_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
 var file_bootstrap_proto_goTypes = []interface{}{
-	(*BootstrapMessage)(nil), // 0: common.BootstrapMessage
+	(*BootstrapHello)(nil),   // 0: common.BootstrapHello
+	(*BootstrapConfirm)(nil), // 1: common.BootstrapConfirm
+	(*BootstrapResult)(nil),  // 2: common.BootstrapResult
 }
 var file_bootstrap_proto_depIdxs = []int32{
 	0, // [0:0] is the sub-list for method output_type
@@ -128,7 +248,31 @@ func file_bootstrap_proto_init() {
 	}
 	if !protoimpl.UnsafeEnabled {
 		file_bootstrap_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*BootstrapMessage); i {
+			switch v := v.(*BootstrapHello); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bootstrap_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BootstrapConfirm); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bootstrap_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BootstrapResult); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -146,7 +290,7 @@ func file_bootstrap_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_bootstrap_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   1,
+			NumMessages:   3,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -2,13 +2,30 @@ package common
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
-	"github.com/bgentry/speakeasy"
+	"google.golang.org/protobuf/proto"
 )
 
+// bootstrap messages are small, this guards us from allocating huge buffer for a malicious length prefix
+const maxBootstrapMessageSize = 64 * 1024
+
+// frame types of bootstrap messages
+const (
+	bootstrapHelloFrame   = 0x1
+	bootstrapConfirmFrame = 0x2
+	bootstrapResultFrame  = 0x3
+)
+
+var bootstrapCodec = FrameCodec{MaxSize: maxBootstrapMessageSize}
+
 type BootstrapMode uint8
 
 const (
@@ -27,10 +44,64 @@ type Bootstrapper struct {
 	ChannelId       string
 	ChannelPassword string
 	ExpectedPeers   int
-	Msg             *BootstrapMessage
+	Param           PeerParam // our own info exchanged with peers
 	Cfg             *TssConfig
 
 	Peers sync.Map // id -> peerInfo
+
+	started    time.Time
+	mismatches sync.Map // moniker@id (or remote address if peer is unknown) -> reason of failed handshake
+}
+
+// BootstrapRejectedError indicates handshake with a peer failed because of different configuration (i.e. channel password),
+// rather than network problem. Such peer might fix their configuration and retry
+type BootstrapRejectedError struct {
+	Reason string
+}
+
+func (e *BootstrapRejectedError) Error() string {
+	return e.Reason
+}
+
+// MalformedBootstrapFrameError indicates the connection does not speak bootstrap protocol,
+// i.e. a libp2p dial of a peer that finished bootstrap earlier (they share the same port)
+type MalformedBootstrapFrameError struct {
+	Reason string
+}
+
+func (e *MalformedBootstrapFrameError) Error() string {
+	return e.Reason
+}
+
+// BootstrapTimeoutError is returned when bootstrap doesn't finish within configured bootstrap timeout
+type BootstrapTimeoutError struct {
+	Timeout time.Duration
+	Report  *BootstrapReport
+}
+
+func (e *BootstrapTimeoutError) Error() string {
+	return fmt.Sprintf("bootstrap did not finish in %v\n%s", e.Timeout, e.Report)
+}
+
+// BootstrapReport summarizes which peers are found, missing or failed handshake with us
+type BootstrapReport struct {
+	Found      []string // moniker@id
+	Missing    []string // moniker@id of peers known from config but not found
+	Unknown    int      // number of peers we are still waiting for but know nothing about (i.e. during keygen)
+	Mismatched []string // "<peer>: <reason>", peer is moniker@id or remote address if we failed before knowing who it is
+}
+
+func (r *BootstrapReport) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "found %d peer(s): %s\n", len(r.Found), strings.Join(r.Found, ", "))
+	fmt.Fprintf(&builder, "missing %d peer(s): %s", len(r.Missing), strings.Join(r.Missing, ", "))
+	if r.Unknown > 0 {
+		fmt.Fprintf(&builder, "\n%d more peer(s) have not been found", r.Unknown)
+	}
+	for _, mismatch := range r.Mismatched {
+		fmt.Fprintf(&builder, "\nmismatched %s", mismatch)
+	}
+	return builder.String()
 }
 
 func NewBootstrapper(expectedPeers int, config *TssConfig) *Bootstrapper {
@@ -41,13 +112,13 @@ func NewBootstrapper(expectedPeers int, config *TssConfig) *Bootstrapper {
 		if err != nil {
 			Panic(err)
 		}
-		if len(channelId) != 11 {
-			Panic(fmt.Errorf("channelId format is invalid"))
-		}
 		config.ChannelId = channelId
 	}
+	if err := ValidateChannelId(config.ChannelId); err != nil {
+		Panic(err)
+	}
 	if config.ChannelPassword == "" {
-		if p, err := speakeasy.Ask("> please input password (AGREED offline with peers) of this session:"); err == nil {
+		if p, err := ReadSecret(config.ChannelPasswordSource, "please input password (AGREED offline with peers) of this session"); err == nil {
 			if p == "" {
 				Panic(fmt.Errorf("channel password should not be empty"))
 			}
@@ -63,11 +134,11 @@ func NewBootstrapper(expectedPeers int, config *TssConfig) *Bootstrapper {
 		config.ListenAddr,
 	)
 
-	bootstrapMsg, err := NewBootstrapMessage(
-		config.ChannelId,
-		config.ChannelPassword,
-		config.ListenAddr,
-		PeerParam{
+	return &Bootstrapper{
+		ChannelId:       config.ChannelId,
+		ChannelPassword: config.ChannelPassword,
+		ExpectedPeers:   expectedPeers,
+		Param: PeerParam{
 			ChannelId: config.ChannelId,
 			Moniker:   config.Moniker,
 			Msg:       config.Message,
@@ -78,63 +149,150 @@ func NewBootstrapper(expectedPeers int, config *TssConfig) *Bootstrapper {
 			NewT:      config.NewThreshold,
 			IsOld:     config.IsOldCommittee,
 			IsNew:     !config.IsOldCommittee,
+			Signers:   sortedCopy(config.Signers),
 		},
-	)
+		Cfg:     config,
+		started: time.Now(),
+	}
+}
+
+// Handshake exchanges peer info with the other side of rw (raw tcp connection or libp2p stream)
+// 1. both sides send BootstrapHello with their pake share
+// 2. both sides send BootstrapConfirm with key confirmation tag and peer info encrypted by session key
+// 3. both sides send BootstrapResult so that a peer with wrong channel password or parameters learns why it is rejected
+// localAddr is the address we can be connected by the peer, remote is only used for logging
+func (b *Bootstrapper) Handshake(rw io.ReadWriter, localAddr, remote string) error {
+	if err := ValidateChannelId(b.ChannelId); err != nil {
+		return err
+	}
+	pake, err := newPake(b.ChannelId, b.ChannelPassword)
 	if err != nil {
-		Panic(err)
+		return err
 	}
 
-	return &Bootstrapper{
-		ChannelId:       config.ChannelId,
-		ChannelPassword: config.ChannelPassword,
-		ExpectedPeers:   expectedPeers,
-		Msg:             bootstrapMsg,
-		Cfg:             config,
+	if err := writeBootstrapFrame(rw, &BootstrapHello{ChannelId: b.ChannelId, Share: pake.share}); err != nil {
+		return err
+	}
+	var peerHello BootstrapHello
+	if err := readBootstrapFrame(rw, &peerHello); err != nil {
+		return err
+	}
+	if peerHello.ChannelId != b.ChannelId {
+		return b.reject(rw, remote, fmt.Sprintf("%s is using a different channel id: %s", remote, peerHello.ChannelId))
+	}
+	keys, err := pake.finish(peerHello.Share)
+	if err != nil {
+		return b.reject(rw, remote, fmt.Sprintf("handshake with %s failed: %v", remote, err))
 	}
-}
 
-func (b *Bootstrapper) HandleBootstrapMsg(peerMsg BootstrapMessage) error {
-	if peerParam, err := Decrypt(peerMsg.PeerInfo, b.ChannelId, b.ChannelPassword); err != nil {
+	param, err := json.Marshal(b.Param)
+	if err != nil {
 		return err
-	} else {
-		if info, ok := b.Peers.Load(peerParam.Id); info != nil && ok {
-			if peerParam.Moniker != info.(PeerInfo).Moniker {
-				return fmt.Errorf("received different moniker for id: %s", peerParam.Id)
-			}
-		} else {
-			if peerParam.Moniker == TssCfg.Moniker {
-				return nil
-			}
-			if peerParam.N != TssCfg.Parties {
-				return fmt.Errorf("received differetnt n for party: %s, %s", peerParam.Moniker, peerParam.Id)
-			}
-			if peerParam.T != TssCfg.Threshold {
-				return fmt.Errorf("received different t for party: %s, %s", peerParam.Moniker, peerParam.Id)
-			}
-			if peerParam.Msg != TssCfg.Message {
-				return fmt.Errorf("received different message to be signed for party: %s, %s", peerParam.Moniker, peerParam.Id)
-			}
-			if peerParam.NewN != TssCfg.NewParties {
-				return fmt.Errorf("received different new n for party: %s, %s", peerParam.Moniker, peerParam.Id)
-			}
-			if peerParam.NewT != TssCfg.NewThreshold {
-				return fmt.Errorf("received different new t for party: %s, %s", peerParam.Moniker, peerParam.Id)
-			}
+	}
+	encrypted, err := encryptWithKey(keys.encryptKey, param)
+	if err != nil {
+		return err
+	}
+	if err := writeBootstrapFrame(rw, &BootstrapConfirm{Tag: keys.ownTag(), PeerInfo: encrypted, Addr: localAddr}); err != nil {
+		return err
+	}
+	var peerConfirm BootstrapConfirm
+	if err := readBootstrapFrame(rw, &peerConfirm); err != nil {
+		return err
+	}
+	if !keys.verifyPeerTag(peerConfirm.Tag) {
+		return b.reject(rw, remote, fmt.Sprintf("%s is using a different channel password", remote))
+	}
+	plaintext, err := decryptWithKey(keys.encryptKey, peerConfirm.PeerInfo)
+	if err != nil {
+		return b.reject(rw, remote, fmt.Sprintf("failed to decrypt peer info of %s: %v", remote, err))
+	}
+	var peerParam PeerParam
+	if err := json.Unmarshal(plaintext, &peerParam); err != nil {
+		return b.reject(rw, remote, fmt.Sprintf("failed to parse peer info of %s: %v", remote, err))
+	}
+	peer := fmt.Sprintf("%s@%s", peerParam.Moniker, peerParam.Id)
+	isSelf, err := b.checkPeerParam(&peerParam)
+	if err != nil {
+		return b.reject(rw, peer, err.Error())
+	}
 
-			pi := PeerInfo{
-				Id:         peerParam.Id,
-				Moniker:    peerParam.Moniker,
-				RemoteAddr: peerMsg.Addr,
-				IsOld:      peerParam.IsOld,
-				IsNew:      peerParam.IsNew,
-			}
-			logger.Debugf("store peer: %s(%s)", peerParam.Moniker, peerParam.Id)
-			b.Peers.Store(peerParam.Id, pi)
-		}
+	if err := writeBootstrapFrame(rw, &BootstrapResult{Accepted: true}); err != nil {
+		return err
+	}
+	var peerResult BootstrapResult
+	if err := readBootstrapFrame(rw, &peerResult); err != nil {
+		return err
+	}
+	if !peerResult.Accepted {
+		reason := fmt.Sprintf("%s(%s) rejected us: %s", peerParam.Moniker, remote, peerResult.Reason)
+		b.mismatches.Store(peer, reason)
+		return &BootstrapRejectedError{reason}
+	}
+
+	// peer might have fixed its configuration and retried
+	b.mismatches.Delete(peer)
+	b.mismatches.Delete(remote)
+	if !isSelf {
+		logger.Debugf("store peer: %s(%s)", peerParam.Moniker, peerParam.Id)
+		b.Peers.Store(peerParam.Id, PeerInfo{
+			Id:         peerParam.Id,
+			Moniker:    peerParam.Moniker,
+			RemoteAddr: peerConfirm.Addr,
+			IsOld:      peerParam.IsOld,
+			IsNew:      peerParam.IsNew,
+		})
 	}
 	return nil
 }
 
+// tell peer why it is rejected (best effort) and return the reason as error, peer is recorded for bootstrap report
+func (b *Bootstrapper) reject(w io.Writer, peer, reason string) error {
+	b.mismatches.Store(peer, reason)
+	if err := writeBootstrapFrame(w, &BootstrapResult{Accepted: false, Reason: reason}); err != nil {
+		logger.Debugf("failed to send bootstrap rejection: %v", err)
+	}
+	return &BootstrapRejectedError{reason}
+}
+
+// checkPeerParam validates peer's parameters are the same with ours, isSelf indicates the peer is ourselves
+func (b *Bootstrapper) checkPeerParam(peerParam *PeerParam) (isSelf bool, err error) {
+	if peerParam.ChannelId != b.ChannelId {
+		return false, fmt.Errorf("wrong channel id of message")
+	}
+	if info, ok := b.Peers.Load(peerParam.Id); info != nil && ok {
+		if peerParam.Moniker != info.(PeerInfo).Moniker {
+			return false, fmt.Errorf("received different moniker for id: %s", peerParam.Id)
+		}
+		return false, nil
+	}
+	if peerParam.Moniker == b.Cfg.Moniker {
+		return true, nil
+	}
+	if peerParam.N != b.Cfg.Parties {
+		return false, fmt.Errorf("received differetnt n for party: %s, %s", peerParam.Moniker, peerParam.Id)
+	}
+	if peerParam.T != b.Cfg.Threshold {
+		return false, fmt.Errorf("received different t for party: %s, %s", peerParam.Moniker, peerParam.Id)
+	}
+	if peerParam.Msg != b.Cfg.Message {
+		return false, fmt.Errorf("received different message to be signed for party: %s, %s", peerParam.Moniker, peerParam.Id)
+	}
+	if peerParam.NewN != b.Cfg.NewParties {
+		return false, fmt.Errorf("received different new n for party: %s, %s", peerParam.Moniker, peerParam.Id)
+	}
+	if peerParam.NewT != b.Cfg.NewThreshold {
+		return false, fmt.Errorf("received different new t for party: %s, %s", peerParam.Moniker, peerParam.Id)
+	}
+	if !b.isChosenSigner(peerParam.Moniker) {
+		return false, fmt.Errorf("%s is not chosen to sign, signers are: %s", peerParam.Moniker, strings.Join(b.Param.Signers, ","))
+	}
+	if strings.Join(peerParam.Signers, ",") != strings.Join(b.Param.Signers, ",") {
+		return false, fmt.Errorf("received different signers for party: %s, %s, theirs: %s", peerParam.Moniker, peerParam.Id, strings.Join(peerParam.Signers, ","))
+	}
+	return false, nil
+}
+
 func (b *Bootstrapper) IsFinished() bool {
 	received := b.LenOfPeers()
 	switch b.Cfg.BMode {
@@ -142,6 +300,11 @@ func (b *Bootstrapper) IsFinished() bool {
 		logger.Debugf("received peers: %d, expect peers: %v", received, b.ExpectedPeers)
 		return received == b.ExpectedPeers
 	case SignMode:
+		if len(b.Cfg.Signers) > 0 {
+			// peers not chosen are rejected during handshake
+			logger.Debugf("received peers: %d, expect signers: %v", received, b.Cfg.Signers)
+			return received == len(b.Cfg.Signers)-1
+		}
 		logger.Debugf("received peers: %d, expect peers: %d", received, b.Cfg.Threshold)
 		return received == b.Cfg.Threshold
 	case PreRegroupMode:
@@ -161,11 +324,11 @@ func (b *Bootstrapper) IsFinished() bool {
 			}
 			return true
 		})
-		if TssCfg.IsOldCommittee && TssCfg.IsNewCommittee {
+		if b.Cfg.IsOldCommittee && b.Cfg.IsNewCommittee {
 			return numOfOld >= b.Cfg.Threshold && numOfNew+1 >= b.Cfg.NewParties
-		} else if TssCfg.IsOldCommittee && !TssCfg.IsNewCommittee {
+		} else if b.Cfg.IsOldCommittee && !b.Cfg.IsNewCommittee {
 			return numOfOld >= b.Cfg.Threshold && numOfNew >= b.Cfg.NewParties
-		} else if !TssCfg.IsOldCommittee && TssCfg.IsNewCommittee {
+		} else if !b.Cfg.IsOldCommittee && b.Cfg.IsNewCommittee {
 			return numOfOld >= b.Cfg.Threshold+1 && numOfNew+1 >= b.Cfg.NewParties
 		} else {
 			return numOfOld >= b.Cfg.Threshold+1 && numOfNew >= b.Cfg.NewParties
@@ -175,6 +338,112 @@ func (b *Bootstrapper) IsFinished() bool {
 	}
 }
 
+// WaitForPeers blocks until IsFinished, or returns BootstrapTimeoutError when Cfg.BootstrapTimeout (if set) elapsed since bootstrapper is created
+func (b *Bootstrapper) WaitForPeers() error {
+	var deadline <-chan time.Time
+	if d := b.Deadline(); !d.IsZero() {
+		timer := time.NewTimer(time.Until(d))
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for !b.IsFinished() {
+		select {
+		case <-deadline:
+			return b.TimeoutError()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Deadline is when bootstrap times out, it is zero if bootstrap waits for peers forever
+func (b *Bootstrapper) Deadline() time.Time {
+	if b.Cfg.BootstrapTimeout <= 0 {
+		return time.Time{}
+	}
+	return b.started.Add(b.Cfg.BootstrapTimeout)
+}
+
+// TimeoutError reports what is found so far once Deadline is passed
+func (b *Bootstrapper) TimeoutError() error {
+	return &BootstrapTimeoutError{b.Cfg.BootstrapTimeout, b.Report()}
+}
+
+// Report tells which peers are found, which known peers are missing and which failed handshake
+func (b *Bootstrapper) Report() *BootstrapReport {
+	report := &BootstrapReport{}
+	found := make(map[string]struct{})
+	b.Peers.Range(func(id, value interface{}) bool {
+		if pi, ok := value.(PeerInfo); ok {
+			found[pi.Id] = struct{}{}
+			report.Found = append(report.Found, fmt.Sprintf("%s@%s", pi.Moniker, pi.Id))
+		}
+		return true
+	})
+
+	known := make(map[string]string) // id -> moniker@id
+	for _, peer := range append(append([]string{}, b.Cfg.ExpectedPeers...), b.Cfg.ExpectedNewPeers...) {
+		parts := strings.SplitN(peer, "@", 2)
+		if len(parts) != 2 || parts[1] == string(b.Cfg.Id) {
+			continue
+		}
+		if b.Cfg.BMode == SignMode && !b.isChosenSigner(parts[0]) {
+			continue
+		}
+		if _, ok := known[parts[1]]; ok {
+			continue
+		}
+		known[parts[1]] = peer
+		if _, ok := found[parts[1]]; !ok {
+			report.Missing = append(report.Missing, peer)
+		}
+	}
+	if b.Cfg.BMode == KeygenMode || b.Cfg.BMode == PreRegroupMode {
+		if unknown := b.ExpectedPeers - len(report.Found) - len(report.Missing); unknown > 0 {
+			report.Unknown = unknown
+		}
+	}
+
+	b.mismatches.Range(func(peer, reason interface{}) bool {
+		// libp2p bootstrap only knows peer id before handshake succeeds
+		if withMoniker, ok := known[peer.(string)]; ok {
+			peer = withMoniker
+		}
+		report.Mismatched = append(report.Mismatched, fmt.Sprintf("%s: %s", peer, reason))
+		return true
+	})
+
+	sort.Strings(report.Found)
+	sort.Strings(report.Missing)
+	sort.Strings(report.Mismatched)
+	return report
+}
+
+// isChosenSigner tells whether moniker can join sign, anyone can if signers are not chosen
+func (b *Bootstrapper) isChosenSigner(moniker string) bool {
+	if len(b.Cfg.Signers) == 0 {
+		return true
+	}
+	for _, signer := range b.Cfg.Signers {
+		if signer == moniker {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedCopy(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	sorted := append([]string{}, s...)
+	sort.Strings(sorted)
+	return sorted
+}
+
 func (b *Bootstrapper) LenOfPeers() int {
 	received := 0
 	b.Peers.Range(func(_, _ interface{}) bool {
@@ -191,3 +460,53 @@ type PeerInfo struct {
 	IsOld      bool
 	IsNew      bool
 }
+
+func bootstrapFrameType(msg proto.Message) byte {
+	switch msg.(type) {
+	case *BootstrapHello:
+		return bootstrapHelloFrame
+	case *BootstrapConfirm:
+		return bootstrapConfirmFrame
+	case *BootstrapResult:
+		return bootstrapResultFrame
+	default:
+		panic(fmt.Sprintf("%T is not a bootstrap message", msg))
+	}
+}
+
+func writeBootstrapFrame(w io.Writer, msg proto.Message) error {
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("bootstrap message cannot be marshaled to protobuf payload: %v", err)
+	}
+	if err := bootstrapCodec.WriteFrame(w, bootstrapFrameType(msg), payload); err != nil {
+		return fmt.Errorf("failed to write bootstrap message: %v", err)
+	}
+	return nil
+}
+
+// readBootstrapFrame reads the next bootstrap message into msg,
+// peer might reject us before we expect its result, in which case BootstrapRejectedError is returned
+func readBootstrapFrame(r io.Reader, msg proto.Message) error {
+	err := bootstrapCodec.ReadFrameFunc(r, func(frameType byte, payload []byte) error {
+		if expected := bootstrapFrameType(msg); frameType != expected {
+			var result BootstrapResult
+			if frameType == bootstrapResultFrame && proto.Unmarshal(payload, &result) == nil && !result.Accepted {
+				return &BootstrapRejectedError{fmt.Sprintf("peer rejected us: %s", result.Reason)}
+			}
+			return &MalformedBootstrapFrameError{fmt.Sprintf("unexpected bootstrap message type: %d, expected: %d", frameType, expected)}
+		}
+		if err := proto.Unmarshal(payload, msg); err != nil {
+			return &MalformedBootstrapFrameError{fmt.Sprintf("failed to unmarshal bootstrap message: %v", err)}
+		}
+		return nil
+	})
+	switch e := err.(type) {
+	case nil, *BootstrapRejectedError, *MalformedBootstrapFrameError:
+		return err
+	case *MalformedFrameError:
+		return &MalformedBootstrapFrameError{e.Reason}
+	default:
+		return fmt.Errorf("failed to read bootstrap message: %v", err)
+	}
+}
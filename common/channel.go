@@ -0,0 +1,65 @@
+package common
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	channelIdRandomBytes  = 16 // 128 bits of randomness, channel id is not secret but should not be guessable
+	channelIdExpireHexLen = 8  // hex encoded int32 epoch seconds
+	ChannelIdLength       = channelIdRandomBytes*2 + channelIdExpireHexLen
+
+	// whoever observes what a channel key protects (rendezvous server, LAN neighbours) can brute-force
+	// channel password against it offline, so key derivation should be expensive
+	channelKdfIterations  = 4
+	channelKdfMemory      = 64 * 1024
+	channelKdfParallelism = 4
+	channelTagBytes       = 16
+)
+
+// NewChannelId generates a channel id which is <random hex><expire time in hex>
+func NewChannelId(expireTime int64) (string, error) {
+	random := make([]byte, channelIdRandomBytes)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(random)) + ConvertTimestampToHex(expireTime), nil
+}
+
+// DeriveChannelKey derives a key from channel id and channel password, purpose separates keys used for different things
+func DeriveChannelKey(channelId, channelPassword, purpose string) []byte {
+	salt := sha256.Sum256([]byte(purpose + channelId))
+	return argon2.IDKey([]byte(channelPassword), salt[:], channelKdfIterations, channelKdfMemory, channelKdfParallelism, 32)
+}
+
+// ChannelTag is a hex keyed hash of data, it can only be computed (and linked to data) by parties knowing the channel key
+func ChannelTag(key []byte, data string) string {
+	return hex.EncodeToString(hmacSha256(key, []byte(data))[:channelTagBytes])
+}
+
+func ChannelIdExpireTime(channelId string) int64 {
+	if len(channelId) < channelIdExpireHexLen {
+		return 0
+	}
+	return int64(ConvertHexToTimestamp(channelId[len(channelId)-channelIdExpireHexLen:]))
+}
+
+func ValidateChannelId(channelId string) error {
+	if len(channelId) != ChannelIdLength {
+		return fmt.Errorf("channelId format is invalid, it should be %d hex characters generated by `tss channel`", ChannelIdLength)
+	}
+	if _, err := hex.DecodeString(channelId); err != nil {
+		return fmt.Errorf("channelId format is invalid: %v", err)
+	}
+	if time.Now().Unix() > ChannelIdExpireTime(channelId) {
+		return fmt.Errorf("channel id has been expired, please regenerate a new one")
+	}
+	return nil
+}
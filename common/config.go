@@ -10,6 +10,7 @@ import (
 	"reflect"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/multiformats/go-multiaddr"
@@ -45,12 +46,38 @@ type P2PConfig struct {
 	// client only config
 	BootstrapPeers       addrList `mapstructure:"bootstraps" json:"bootstraps"`
 	RelayPeers           addrList `mapstructure:"relays" json:"relays"`
-	PeerAddrs            []string `mapstructure:"peer_addrs" json:"peer_addrs"`         // used for some peer has known connectable ip:port so that connection to them doesn't require bootstrap and relay nodes. i.e. in a LAN environment, if ip ports are preallocated, BootstrapPeers and RelayPeers can be empty with all parties host port set
-	ExpectedPeers        []string `mapstructure:"peers" json:"peers"`                   // expected peer list, <moniker>@<TssClientId>
-	NewPeerAddrs         []string `mapstructure:"new_peer_addrs" json:"new_peer_addrs"` // same with `PeerAddrs` but for new parties for regroup
-	ExpectedNewPeers     []string `mapstructure:"new_peers" json:"new_peers"`           // expected new peer list used for regroup, <moniker>@<TssClientId>, after regroup success, this field will replace ExpectedPeers
+	PeerAddrs            []string `mapstructure:"peer_addrs" json:"peer_addrs"`                   // used for some peer has known connectable ip:port so that connection to them doesn't require bootstrap and relay nodes. i.e. in a LAN environment, if ip ports are preallocated, BootstrapPeers and RelayPeers can be empty with all parties host port set
+	ExpectedPeers        []string `mapstructure:"peers" json:"peers"`                             // expected peer list, <moniker>@<TssClientId>
+	NewPeerAddrs         []string `mapstructure:"new_peer_addrs" json:"new_peer_addrs"`           // same with `PeerAddrs` but for new parties for regroup
+	ExpectedNewPeers     []string `mapstructure:"new_peers" json:"new_peers"`                     // expected new peer list used for regroup, <moniker>@<TssClientId>, after regroup success, this field will replace ExpectedPeers
+	Rendezvous           string   `mapstructure:"rendezvous" json:"rendezvous"`                   // url of rendezvous server (see `tss server`) used to find peers not in the same LAN, i.e. http://1.2.3.4:27149
+	Discovery            string   `mapstructure:"discovery" json:"discovery"`                     // how to find peers in the same LAN: ssdp (default) or mdns
+	DiscoveryInterface   string   `mapstructure:"discovery_interface" json:"discovery_interface"` // network interface used by discovery, all multicast capable ones if empty
 	DefaultBootstap      bool     `mapstructure:"default_bootstrap", json:"default_bootstrap"`
 	BroadcastSanityCheck bool     `mapstructure:"broadcast_sanity_check" json:"-"`
+	BroadcastMode        string   `mapstructure:"broadcast_mode" json:"-"`   // sanity_check (default), reliable or none
+	MaxMessageSize       int      `mapstructure:"max_message_size" json:"-"` // maximum size in bytes of a message from peers, DefaultMaxFrameSize if 0
+}
+
+const (
+	BroadcastModeNone        = "none"
+	BroadcastModeSanityCheck = "sanity_check"
+	BroadcastModeReliable    = "reliable"
+)
+
+// GetBroadcastMode returns how broadcast messages are checked, --p2p.broadcast_sanity_check=false is kept for compatibility and means none
+func (c *P2PConfig) GetBroadcastMode() (string, error) {
+	switch c.BroadcastMode {
+	case "":
+		if c.BroadcastSanityCheck {
+			return BroadcastModeSanityCheck, nil
+		}
+		return BroadcastModeNone, nil
+	case BroadcastModeNone, BroadcastModeSanityCheck, BroadcastModeReliable:
+		return c.BroadcastMode, nil
+	default:
+		return "", fmt.Errorf("unknown broadcast mode %s, should be %s, %s or %s", c.BroadcastMode, BroadcastModeSanityCheck, BroadcastModeReliable, BroadcastModeNone)
+	}
 }
 
 // Argon2 parameters, setting should refer 9th section of https://github.com/P-H-C/phc-winner-argon2/blob/master/argon2-specs.pdf
@@ -92,10 +119,22 @@ type TssConfig struct {
 	LogLevel    string `mapstructure:"log_level" json:"log_level"`
 	ProfileAddr string `mapstructure:"profile_addr" json:"profile_addr"`
 	Password    string `json:"-"`
-	Message     string `json:"-"` // string represented big.Int, will refactor later
+	// where to read Password from when it is not given, see SecretProvider
+	PasswordSource string `mapstructure:"password_source" json:"-"`
+	Message        string `json:"-"` // string represented big.Int, will refactor later
+	// monikers (including this party) chosen to sign, sign waits for exactly them rather than whoever online first
+	Signers []string `mapstructure:"signers" json:"-"`
 
 	ChannelId       string `mapstructure:"channel_id" json:"-"`
 	ChannelPassword string `mapstructure:"channel_password" json:"-"`
+	// where to read ChannelPassword from when it is not given, see SecretProvider
+	ChannelPasswordSource string `mapstructure:"channel_password_source" json:"-"`
+	// start keygen, sign or regroup from imported committee manifest rather than bootstrapping
+	UseManifest bool `mapstructure:"manifest" json:"-"`
+	// bootstrap addresses of ceremony initiator from invitation, they are dialed besides peers found by ssdp or rendezvous
+	InitiatorAddrs []string `mapstructure:"-" json:"-"`
+	// how long to wait for peers during bootstrap before giving up with a report, 0 means wait forever
+	BootstrapTimeout time.Duration `mapstructure:"bootstrap_timeout" json:"-"`
 
 	IsOldCommittee bool          `mapstructure:"is_old" json:"-"`
 	IsNewCommittee bool          `mapstructure:"is_new_member" json:"-"`
@@ -128,7 +167,7 @@ func ReadConfigFromHome(v *viper.Viper, init bool, home, vault, passphrase strin
 
 	var config TssConfig
 	err = v.Unmarshal(&config, func(config *mapstructure.DecoderConfig) {
-		config.DecodeHook = func(from, to reflect.Type, data interface{}) (interface{}, error) {
+		config.DecodeHook = mapstructure.ComposeDecodeHookFunc(mapstructure.StringToTimeDurationHookFunc(), func(from, to reflect.Type, data interface{}) (interface{}, error) {
 			if from.Kind() == reflect.Slice && from.Elem().Kind() == reflect.String && to == reflect.TypeOf(addrList{}) {
 				var al addrList
 				for _, value := range data.([]string) {
@@ -152,7 +191,7 @@ func ReadConfigFromHome(v *viper.Viper, init bool, home, vault, passphrase strin
 				return al, nil
 			}
 			return data, nil
-		}
+		})
 	})
 	if err != nil {
 		return err
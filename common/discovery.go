@@ -0,0 +1,73 @@
+package common
+
+import (
+	"fmt"
+	"net"
+)
+
+const (
+	DiscoverySsdp = "ssdp"
+	DiscoveryMdns = "mdns"
+)
+
+// Discovery finds listen addresses of peers in the same LAN before raw tcp bootstrapping,
+// implementations are ssdp.SsdpService and mdns.MdnsService
+type Discovery interface {
+	// CollectPeerAddrs advertises our listen addresses and calls found for each newly found peer,
+	// it blocks until expected number of peers are found or it is closed.
+	// Advertising goes on after it returns because peers might not have found us yet
+	CollectPeerAddrs(found func(peerAddr string)) error
+	// Close stops advertising, it should be called after bootstrapping finished
+	Close() error
+}
+
+// MulticastInterfaces returns interface named name, or all interfaces that are up, multicast capable and have an ipv4 address if name is empty
+// Both ssdp and mdns discovery only speak ipv4 multicast, so an ipv6 only interface is an error if it is named explicitly
+func MulticastInterfaces(name string) ([]net.Interface, error) {
+	if name != "" {
+		ifi, err := net.InterfaceByName(name)
+		if err != nil {
+			return nil, fmt.Errorf("cannot find network interface %s: %v", name, err)
+		}
+		if ifi.Flags&net.FlagUp == 0 || ifi.Flags&net.FlagMulticast == 0 {
+			return nil, fmt.Errorf("network interface %s is not up or does not support multicast", name)
+		}
+		if !hasIPv4Addr(*ifi) {
+			return nil, fmt.Errorf("network interface %s has no ipv4 address, lan discovery only supports ipv4 multicast", name)
+		}
+		return []net.Interface{*ifi}, nil
+	}
+
+	all, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	ifaces := make([]net.Interface, 0, len(all))
+	for _, ifi := range all {
+		if ifi.Flags&net.FlagUp == 0 || ifi.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		if !hasIPv4Addr(ifi) {
+			logger.Debugf("skip network interface %s without ipv4 address for lan discovery", ifi.Name)
+			continue
+		}
+		ifaces = append(ifaces, ifi)
+	}
+	if len(ifaces) == 0 {
+		return nil, fmt.Errorf("no network interface supports multicast, please set --p2p.discovery_interface or peer addresses")
+	}
+	return ifaces, nil
+}
+
+func hasIPv4Addr(ifi net.Interface) bool {
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package common
+
+import (
+	"filippo.io/edwards25519/field"
+)
+
+// Elligator 2 map onto curve25519 (RFC 9380, appendix G.2.1), used to map channel id and password to the CPace
+// generator (see pake.go). Field arithmetic is filippo.io/edwards25519/field, which is constant time, and the map
+// doesn't branch on secret values, so the time it takes doesn't tell anything about the password
+
+// Montgomery coefficient A of curve25519
+var feJ = new(field.Element).Mult32(new(field.Element).One(), 486662)
+
+// elligator2 maps u to the u-coordinate of a point on curve25519, RFC 9380 map_to_curve_elligator2_curve25519
+// without the y-coordinate, which X25519 doesn't need
+func elligator2(u *field.Element) [32]byte {
+	var tv1, tv2, xd, x1n, x2n, gxd, gx1, xn, x, y field.Element
+	tv1.Square(u)
+	tv1.Add(&tv1, &tv1)                    // 2u^2
+	xd.Add(&tv1, new(field.Element).One()) // nonzero: -1 is square (mod p), 2u^2 is not
+	x1n.Negate(feJ)                        // x1 = -J / (1 + 2u^2)
+	tv2.Square(&xd)                        //
+	gxd.Multiply(&tv2, &xd)                // xd^3
+	gx1.Multiply(feJ, &tv1)                // x1n + J * xd
+	gx1.Multiply(&gx1, &x1n)               // x1n^2 + J * x1n * xd
+	gx1.Add(&gx1, &tv2)                    // x1n^2 + J * x1n * xd + xd^2
+	gx1.Multiply(&gx1, &x1n)               // x1n^3 + J * x1n^2 * xd + x1n * xd^2
+	// x1 is on curve iff g(x1) = gx1 / gxd is square, otherwise x2 = 2u^2 * x1 is
+	_, isSquare := y.SqrtRatio(&gx1, &gxd)
+	x2n.Multiply(&x1n, &tv1)
+	xn.Select(&x1n, &x2n, isSquare)
+	x.Invert(&xd)
+	x.Multiply(&x, &xn)
+	var out [32]byte
+	copy(out[:], x.Bytes())
+	return out
+}
@@ -0,0 +1,158 @@
+package common
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+)
+
+// Messages on party streams and bootstrap connections (libp2p streams or raw tcp) are framed the same way:
+//
+//	| version (1 byte) | type (1 byte) | payload length (4 bytes, big endian) | payload |
+//
+// The header is checked before anything is allocated for the payload,
+// so that a peer speaking another version or claiming a huge length is rejected early.
+// The payload buffer grows as the payload is received, so that a peer claiming a large length within the limit
+// doesn't make us allocate it before sending it
+const (
+	FrameVersion        = 0x1
+	FrameHeaderLength   = 1 + 1 + 4
+	DefaultMaxFrameSize = 64 * 1024 * 1024
+
+	maxPooledFrameBuffer = 1024 * 1024 // larger buffers are not put back to pool, otherwise a few big messages would pin a lot of memory
+	frameReadChunk       = 64 * 1024   // payload is read in chunks of this size at most, buffer is grown for each chunk
+)
+
+// MalformedFrameError indicates the peer doesn't speak our framing, i.e. it is running another version or sending garbage
+type MalformedFrameError struct {
+	Reason string
+}
+
+func (e *MalformedFrameError) Error() string {
+	return e.Reason
+}
+
+var framePool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, 4096)
+		return &buf
+	},
+}
+
+func getFrameBuffer(size int) *[]byte {
+	buf := framePool.Get().(*[]byte)
+	if cap(*buf) < size {
+		*buf = make([]byte, size)
+	}
+	*buf = (*buf)[:size]
+	return buf
+}
+
+func putFrameBuffer(buf *[]byte) {
+	if cap(*buf) <= maxPooledFrameBuffer {
+		framePool.Put(buf)
+	}
+}
+
+// FrameCodec reads and writes frames, the zero value accepts payloads up to DefaultMaxFrameSize
+type FrameCodec struct {
+	MaxSize int // maximum payload length in bytes we accept from peer
+}
+
+func (c FrameCodec) maxSize() int {
+	if c.MaxSize <= 0 {
+		return DefaultMaxFrameSize
+	}
+	return c.MaxSize
+}
+
+// WriteFrame writes header and payload in one write, so that frames of concurrent writers don't interleave
+func (c FrameCodec) WriteFrame(w io.Writer, frameType byte, payload []byte) error {
+	if uint64(len(payload)) > math.MaxUint32 {
+		return fmt.Errorf("message is too large: %d bytes", len(payload))
+	}
+	buf := getFrameBuffer(FrameHeaderLength + len(payload))
+	defer putFrameBuffer(buf)
+	frame := *buf
+	frame[0] = FrameVersion
+	frame[1] = frameType
+	binary.BigEndian.PutUint32(frame[2:FrameHeaderLength], uint32(len(payload)))
+	copy(frame[FrameHeaderLength:], payload)
+	_, err := w.Write(frame)
+	return err
+}
+
+// ReadFrame reads a frame, the payload returned is owned by caller
+func (c FrameCodec) ReadFrame(r io.Reader) (byte, []byte, error) {
+	frameType, length, err := c.readHeader(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	payload, err := readPayload(r, nil, length)
+	if err != nil {
+		return 0, nil, err
+	}
+	return frameType, payload, nil
+}
+
+// ReadFrameFunc reads a frame into a pooled buffer and passes it to handle,
+// payload must not be retained after handle returns, i.e. it should be unmarshaled or copied right away
+func (c FrameCodec) ReadFrameFunc(r io.Reader, handle func(frameType byte, payload []byte) error) error {
+	frameType, length, err := c.readHeader(r)
+	if err != nil {
+		return err
+	}
+	buf := framePool.Get().(*[]byte)
+	defer putFrameBuffer(buf)
+	*buf, err = readPayload(r, *buf, length)
+	if err != nil {
+		return err
+	}
+	return handle(frameType, *buf)
+}
+
+// readPayload reads length bytes into buf (reused from 0), it grows buf at most twice of what is received so far
+func readPayload(r io.Reader, buf []byte, length int) ([]byte, error) {
+	buf = buf[:0]
+	for len(buf) < length {
+		chunk := length - len(buf)
+		if chunk > frameReadChunk {
+			chunk = frameReadChunk
+		}
+		if cap(buf)-len(buf) < chunk {
+			size := 2 * cap(buf)
+			if size < len(buf)+chunk {
+				size = len(buf) + chunk
+			}
+			if size > length {
+				size = length
+			}
+			grown := make([]byte, len(buf), size)
+			copy(grown, buf)
+			buf = grown
+		}
+		n, err := io.ReadFull(r, buf[len(buf):len(buf)+chunk])
+		buf = buf[:len(buf)+n]
+		if err != nil {
+			return buf, err
+		}
+	}
+	return buf, nil
+}
+
+func (c FrameCodec) readHeader(r io.Reader) (byte, int, error) {
+	var header [FrameHeaderLength]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, 0, err
+	}
+	if header[0] != FrameVersion {
+		return 0, 0, &MalformedFrameError{fmt.Sprintf("unsupported frame version %d (we speak %d), peer might be running another version", header[0], FrameVersion)}
+	}
+	length := binary.BigEndian.Uint32(header[2:])
+	if uint64(length) > uint64(c.maxSize()) {
+		return 0, 0, &MalformedFrameError{fmt.Sprintf("invalid message length: %d, maximum: %d", length, c.maxSize())}
+	}
+	return header[1], int(length), nil
+}
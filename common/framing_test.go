@@ -0,0 +1,75 @@
+package common
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"runtime"
+	"testing"
+)
+
+func TestReadFrameRoundTrip(t *testing.T) {
+	codec := FrameCodec{}
+	payloads := [][]byte{{}, []byte("hello"), bytes.Repeat([]byte{0xab}, 3*frameReadChunk+17)}
+	var stream bytes.Buffer
+	for _, payload := range payloads {
+		if err := codec.WriteFrame(&stream, 0x7, payload); err != nil {
+			t.Fatal(err)
+		}
+		if err := codec.WriteFrame(&stream, 0x8, payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, payload := range payloads {
+		frameType, read, err := codec.ReadFrame(&stream)
+		if err != nil || frameType != 0x7 || !bytes.Equal(read, payload) {
+			t.Fatalf("frame of %d bytes is not read back: type %d, %d bytes, %v", len(payload), frameType, len(read), err)
+		}
+		err = codec.ReadFrameFunc(&stream, func(frameType byte, read []byte) error {
+			if frameType != 0x8 || !bytes.Equal(read, payload) {
+				t.Fatalf("frame of %d bytes is not read back: type %d, %d bytes", len(payload), frameType, len(read))
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadFrameRejectsOversizedLength(t *testing.T) {
+	codec := FrameCodec{MaxSize: 1024}
+	var stream bytes.Buffer
+	if err := (FrameCodec{}).WriteFrame(&stream, 0x7, make([]byte, 1025)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := codec.ReadFrame(&stream); err == nil {
+		t.Fatal("frame larger than maximum size should be rejected")
+	} else if _, ok := err.(*MalformedFrameError); !ok {
+		t.Fatalf("expected MalformedFrameError, got %v", err)
+	}
+}
+
+// a peer claiming the maximum length but sending a few bytes makes us allocate about what it sent
+func TestReadFrameAllocatesWhatIsReceived(t *testing.T) {
+	header := make([]byte, FrameHeaderLength)
+	header[0] = FrameVersion
+	binary.BigEndian.PutUint32(header[2:], DefaultMaxFrameSize)
+	truncated := append(header, make([]byte, 100)...)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := 0; i < 10; i++ {
+		if _, _, err := (FrameCodec{}).ReadFrame(bytes.NewReader(truncated)); err != io.ErrUnexpectedEOF {
+			t.Fatalf("truncated frame should fail with unexpected EOF, got %v", err)
+		}
+		err := (FrameCodec{}).ReadFrameFunc(bytes.NewReader(truncated), func(byte, []byte) error { return nil })
+		if err != io.ErrUnexpectedEOF {
+			t.Fatalf("truncated frame should fail with unexpected EOF, got %v", err)
+		}
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 20*2*frameReadChunk {
+		t.Fatalf("%d bytes are allocated for 20 truncated frames", allocated)
+	}
+}
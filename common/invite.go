@@ -0,0 +1,112 @@
+package common
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	InviteCeremonyKeygen  = "keygen"
+	InviteCeremonySign    = "sign"
+	InviteCeremonyRegroup = "regroup"
+
+	invitePrefix      = "tssinv1:"
+	inviteChecksumLen = 4
+)
+
+// Invitation bundles everything (except the channel password) parties need to agree on before a ceremony,
+// so that the initiator can share one string (or QR code) rather than letting everyone type them into prompts
+// Channel password is deliberately excluded, it should be shared via another channel
+type Invitation struct {
+	ChannelId    string   `json:"c"` // expire time is the last part of channel id
+	Ceremony     string   `json:"k"`
+	Parties      int      `json:"n,omitempty"`
+	Threshold    int      `json:"t,omitempty"`
+	NewParties   int      `json:"nn,omitempty"`
+	NewThreshold int      `json:"nt,omitempty"`
+	Rendezvous   string   `json:"r,omitempty"`
+	Addrs        []string `json:"a,omitempty"` // bootstrap multiaddrs of initiator
+}
+
+func (i *Invitation) Expire() time.Time {
+	return time.Unix(ChannelIdExpireTime(i.ChannelId), 0)
+}
+
+// Encode returns tssinv1:<base64url(json || checksum)>, checksum catches typos when the string is typed by hand
+func (i *Invitation) Encode() (string, error) {
+	payload, err := json.Marshal(i)
+	if err != nil {
+		return "", err
+	}
+	checksum := sha256.Sum256(payload)
+	return invitePrefix + base64.RawURLEncoding.EncodeToString(append(payload, checksum[:inviteChecksumLen]...)), nil
+}
+
+func DecodeInvitation(invite string) (*Invitation, error) {
+	invite = strings.TrimSpace(invite)
+	if !strings.HasPrefix(invite, invitePrefix) {
+		return nil, fmt.Errorf("invitation should start with %s", invitePrefix)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(invite, invitePrefix))
+	if err != nil || len(raw) <= inviteChecksumLen {
+		return nil, fmt.Errorf("invitation is malformed")
+	}
+	payload, checksum := raw[:len(raw)-inviteChecksumLen], raw[len(raw)-inviteChecksumLen:]
+	if expected := sha256.Sum256(payload); !bytes.Equal(checksum, expected[:inviteChecksumLen]) {
+		return nil, fmt.Errorf("invitation checksum mismatch, please check it is completely copied")
+	}
+
+	var i Invitation
+	if err := json.Unmarshal(payload, &i); err != nil {
+		return nil, fmt.Errorf("invitation is malformed: %v", err)
+	}
+	if err := ValidateChannelId(i.ChannelId); err != nil {
+		return nil, err
+	}
+	switch i.Ceremony {
+	case InviteCeremonyKeygen, InviteCeremonySign, InviteCeremonyRegroup:
+	default:
+		return nil, fmt.Errorf("unknown ceremony in invitation: %s", i.Ceremony)
+	}
+	return &i, nil
+}
+
+// Apply fills config with invitation for the given ceremony, values already set explicitly (non-zero) must agree with invitation
+func (i *Invitation) Apply(config *TssConfig, ceremony string) error {
+	if i.Ceremony != ceremony {
+		return fmt.Errorf("invitation is for %s rather than %s", i.Ceremony, ceremony)
+	}
+	params := []struct {
+		name  string
+		field *int
+		value int
+	}{
+		{"parties", &config.Parties, i.Parties},
+		{"threshold", &config.Threshold, i.Threshold},
+		{"new_parties", &config.NewParties, i.NewParties},
+		{"new_threshold", &config.NewThreshold, i.NewThreshold},
+	}
+	for _, p := range params {
+		if p.value == 0 {
+			continue
+		}
+		if *p.field != 0 && *p.field != p.value {
+			return fmt.Errorf("%s is %d in invitation, but %d locally", p.name, p.value, *p.field)
+		}
+		*p.field = p.value
+	}
+	if config.ChannelId != "" && config.ChannelId != i.ChannelId {
+		return fmt.Errorf("channel id in invitation is different from --channel_id")
+	}
+	config.ChannelId = i.ChannelId
+	if i.Rendezvous != "" {
+		config.Rendezvous = i.Rendezvous
+	}
+	config.InitiatorAddrs = i.Addrs
+	return nil
+}
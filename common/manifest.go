@@ -0,0 +1,367 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+const (
+	ManifestRoleMember = "member" // party of keygen
+	ManifestRoleSigner = "signer" // party participating sign
+	ManifestRoleOld    = "old"    // old committee party participating regroup
+	ManifestRoleNew    = "new"    // new committee party of regroup
+
+	manifestVersion  = 1
+	manifestFileName = "manifest.json"
+	manifestSignDST  = "tss-committee-manifest-v1"
+)
+
+// Manifest is a committee configuration agreed offline, it lists every party of a keygen, sign or regroup session
+// Each listed party signs the manifest with its libp2p node key, so that an imported manifest can replace
+// ssdp, channel id and channel password based bootstrapping. The manifest is bound to a channel id (from `tss channel`),
+// so that it expires with the channel id and cannot be replayed into a later session of the same committee
+type Manifest struct {
+	Version      int                 `json:"version"`
+	ChannelId    string              `json:"channel_id"`
+	Threshold    int                 `json:"threshold"`
+	Parties      int                 `json:"parties"`
+	NewThreshold int                 `json:"new_threshold,omitempty"`
+	NewParties   int                 `json:"new_parties,omitempty"`
+	Members      []ManifestMember    `json:"members"`
+	Signatures   []ManifestSignature `json:"signatures,omitempty"`
+}
+
+type ManifestMember struct {
+	Moniker string   `json:"moniker"`
+	Id      string   `json:"id"`    // libp2p id, which is also the public key verifying this member's signature
+	Addrs   []string `json:"addrs"` // libp2p multiaddrs this member can be connected
+	Role    string   `json:"role"`
+}
+
+type ManifestSignature struct {
+	Id        string `json:"id"`
+	Signature []byte `json:"signature"`
+}
+
+func NewManifest(channelId string, threshold, parties, newThreshold, newParties int) *Manifest {
+	return &Manifest{
+		Version:      manifestVersion,
+		ChannelId:    channelId,
+		Threshold:    threshold,
+		Parties:      parties,
+		NewThreshold: newThreshold,
+		NewParties:   newParties,
+	}
+}
+
+func LoadManifest(file string) (*Manifest, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(content, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %v", file, err)
+	}
+	return &m, nil
+}
+
+func (m *Manifest) Save(file string) error {
+	content, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, content, 0600)
+}
+
+// AddMember adds or replaces (with same id) a member, existing signatures are dropped as the content is changed
+func (m *Manifest) AddMember(member ManifestMember) error {
+	if err := validateManifestRole(member.Role); err != nil {
+		return err
+	}
+	if _, err := peer.IDB58Decode(member.Id); err != nil {
+		return fmt.Errorf("invalid id of %s: %v", member.Moniker, err)
+	}
+	replaced := false
+	for i, existing := range m.Members {
+		if existing.Id == member.Id {
+			m.Members[i] = member
+			replaced = true
+		} else if existing.Moniker == member.Moniker {
+			return fmt.Errorf("moniker %s is already used by %s", member.Moniker, existing.Id)
+		}
+	}
+	if !replaced {
+		m.Members = append(m.Members, member)
+	}
+	m.Signatures = nil
+	return nil
+}
+
+func (m *Manifest) Member(id string) (*ManifestMember, bool) {
+	for i := range m.Members {
+		if m.Members[i].Id == id {
+			return &m.Members[i], true
+		}
+	}
+	return nil, false
+}
+
+// Sign adds (or replaces) signature of the member owning privKey
+func (m *Manifest) Sign(privKey crypto.PrivKey) error {
+	id, err := peer.IDFromPrivateKey(privKey)
+	if err != nil {
+		return err
+	}
+	if _, ok := m.Member(id.Pretty()); !ok {
+		return fmt.Errorf("%s is not a member of the manifest", id.Pretty())
+	}
+	data, err := m.signingBytes()
+	if err != nil {
+		return err
+	}
+	signature, err := privKey.Sign(data)
+	if err != nil {
+		return err
+	}
+	signatures := make([]ManifestSignature, 0, len(m.Signatures)+1)
+	for _, s := range m.Signatures {
+		if s.Id != id.Pretty() {
+			signatures = append(signatures, s)
+		}
+	}
+	m.Signatures = append(signatures, ManifestSignature{Id: id.Pretty(), Signature: signature})
+	return nil
+}
+
+// Verify checks the manifest is well formed and signed by all of its members
+func (m *Manifest) Verify() error {
+	if m.Version != manifestVersion {
+		return fmt.Errorf("unsupported manifest version: %d", m.Version)
+	}
+	if err := ValidateChannelId(m.ChannelId); err != nil {
+		return fmt.Errorf("invalid channel id in manifest: %v", err)
+	}
+	if m.Threshold <= 0 || m.Threshold+1 > m.Parties {
+		return fmt.Errorf("invalid threshold %d of %d parties in manifest", m.Threshold, m.Parties)
+	}
+	if m.NewParties > 0 && (m.NewThreshold <= 0 || m.NewThreshold+1 > m.NewParties) {
+		return fmt.Errorf("invalid new threshold %d of %d new parties in manifest", m.NewThreshold, m.NewParties)
+	}
+
+	data, err := m.signingBytes()
+	if err != nil {
+		return err
+	}
+	signatures := make(map[string][]byte, len(m.Signatures))
+	for _, s := range m.Signatures {
+		signatures[s.Id] = s.Signature
+	}
+	monikers := make(map[string]struct{}, len(m.Members))
+	for _, member := range m.Members {
+		if err := validateManifestRole(member.Role); err != nil {
+			return err
+		}
+		if _, ok := monikers[member.Moniker]; ok {
+			return fmt.Errorf("duplicated moniker in manifest: %s", member.Moniker)
+		}
+		monikers[member.Moniker] = struct{}{}
+
+		signature, ok := signatures[member.Id]
+		if !ok {
+			return fmt.Errorf("%s(%s) has not signed the manifest", member.Moniker, member.Id)
+		}
+		delete(signatures, member.Id)
+		id, err := peer.IDB58Decode(member.Id)
+		if err != nil {
+			return fmt.Errorf("invalid id of %s: %v", member.Moniker, err)
+		}
+		pubKey, err := id.ExtractPublicKey()
+		if err != nil {
+			return fmt.Errorf("cannot extract public key from id of %s: %v", member.Moniker, err)
+		}
+		if valid, err := pubKey.Verify(data, signature); err != nil || !valid {
+			return fmt.Errorf("invalid manifest signature of %s(%s)", member.Moniker, member.Id)
+		}
+	}
+	for id := range signatures {
+		return fmt.Errorf("manifest is signed by %s, who is not a member", id)
+	}
+	return nil
+}
+
+// Signers returns monikers of parties holding shares and participating this session (sign or regroup)
+func (m *Manifest) Signers() []string {
+	var signers []string
+	for _, member := range m.Members {
+		if member.Role == ManifestRoleSigner || member.Role == ManifestRoleOld {
+			signers = append(signers, member.Moniker)
+		}
+	}
+	return signers
+}
+
+// Apply fills peers and parameters of config from the manifest, so that bootstrapping can be skipped
+// peers are kept in manifest order, so that all parties get the same index-aligned ExpectedPeers and PeerAddrs
+func (m *Manifest) Apply(config *TssConfig, mode BootstrapMode) error {
+	self, ok := m.Member(string(config.Id))
+	if !ok {
+		return fmt.Errorf("this party (%s) is not a member of the manifest", config.Id)
+	}
+	if self.Moniker != config.Moniker {
+		return fmt.Errorf("moniker of this party in manifest is %s, rather than %s", self.Moniker, config.Moniker)
+	}
+	if config.ChannelId != "" && config.ChannelId != m.ChannelId {
+		return fmt.Errorf("channel id in manifest is different from --channel_id")
+	}
+	config.ChannelId = m.ChannelId
+	roles := make(map[string]int)
+	for _, member := range m.Members {
+		roles[member.Role]++
+	}
+
+	switch mode {
+	case KeygenMode:
+		if roles[ManifestRoleMember] != len(m.Members) || len(m.Members) != m.Parties {
+			return fmt.Errorf("keygen manifest should list all %d parties as %s", m.Parties, ManifestRoleMember)
+		}
+		config.Parties = m.Parties
+		config.Threshold = m.Threshold
+		config.ExpectedPeers, config.PeerAddrs = m.peersOf(config, ManifestRoleMember)
+	case SignMode:
+		if config.Parties != m.Parties || config.Threshold != m.Threshold {
+			return fmt.Errorf("manifest is for a %d-of-%d scheme, but this vault is %d-of-%d", m.Threshold+1, m.Parties, config.Threshold+1, config.Parties)
+		}
+		if self.Role != ManifestRoleSigner || roles[ManifestRoleSigner] != len(m.Members) || len(m.Members) < m.Threshold+1 {
+			return fmt.Errorf("sign manifest should list at least %d parties (including this one) as %s", m.Threshold+1, ManifestRoleSigner)
+		}
+		if err := m.updatePeerAddrs(config, ManifestRoleSigner); err != nil {
+			return err
+		}
+	case PreRegroupMode, RegroupMode:
+		if m.NewParties == 0 || roles[ManifestRoleOld] < m.Threshold+1 || roles[ManifestRoleNew] != m.NewParties || roles[ManifestRoleOld]+roles[ManifestRoleNew] != len(m.Members) {
+			return fmt.Errorf("regroup manifest should list at least %d parties as %s and %d parties as %s", m.Threshold+1, ManifestRoleOld, m.NewParties, ManifestRoleNew)
+		}
+		config.IsOldCommittee = self.Role == ManifestRoleOld
+		config.IsNewCommittee = self.Role == ManifestRoleNew
+		if config.IsOldCommittee {
+			if config.Parties != m.Parties || config.Threshold != m.Threshold {
+				return fmt.Errorf("manifest is for a %d-of-%d scheme, but this vault is %d-of-%d", m.Threshold+1, m.Parties, config.Threshold+1, config.Parties)
+			}
+			if err := m.updatePeerAddrs(config, ManifestRoleOld); err != nil {
+				return err
+			}
+		} else {
+			config.Parties = m.Parties
+			config.Threshold = m.Threshold
+			config.ExpectedPeers, config.PeerAddrs = m.peersOf(config, ManifestRoleOld)
+		}
+		config.NewParties = m.NewParties
+		config.NewThreshold = m.NewThreshold
+		config.ExpectedNewPeers, config.NewPeerAddrs = m.peersOf(config, ManifestRoleNew)
+	default:
+		return fmt.Errorf("manifest cannot be used in bootstrap mode %d", mode)
+	}
+	return nil
+}
+
+// peersOf returns other members of role in <moniker>@<id> and their first address
+func (m *Manifest) peersOf(config *TssConfig, role string) (expectedPeers, peerAddrs []string) {
+	expectedPeers = make([]string, 0)
+	peerAddrs = make([]string, 0)
+	for _, member := range m.Members {
+		if member.Role != role || member.Id == string(config.Id) {
+			continue
+		}
+		expectedPeers = append(expectedPeers, fmt.Sprintf("%s@%s", member.Moniker, member.Id))
+		addr := ""
+		if len(member.Addrs) > 0 {
+			addr = member.Addrs[0]
+		}
+		peerAddrs = append(peerAddrs, addr)
+	}
+	return
+}
+
+// updatePeerAddrs replaces addresses of members of role in config, these members must have been known since keygen
+func (m *Manifest) updatePeerAddrs(config *TssConfig, role string) error {
+	for len(config.PeerAddrs) < len(config.ExpectedPeers) {
+		config.PeerAddrs = append(config.PeerAddrs, "")
+	}
+	for _, member := range m.Members {
+		if member.Role != role || member.Id == string(config.Id) {
+			continue
+		}
+		found := false
+		for i, expectedPeer := range config.ExpectedPeers {
+			if expectedPeer == fmt.Sprintf("%s@%s", member.Moniker, member.Id) {
+				if len(member.Addrs) > 0 {
+					config.PeerAddrs[i] = member.Addrs[0]
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s(%s) in manifest is not a party of this vault", member.Moniker, member.Id)
+		}
+	}
+	return nil
+}
+
+// members sign the manifest without signatures, go's json encoding of struct is deterministic
+func (m *Manifest) signingBytes() ([]byte, error) {
+	unsigned := *m
+	unsigned.Signatures = nil
+	content, err := json.Marshal(&unsigned)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(manifestSignDST), content...), nil
+}
+
+func validateManifestRole(role string) error {
+	switch role {
+	case ManifestRoleMember, ManifestRoleSigner, ManifestRoleOld, ManifestRoleNew:
+		return nil
+	default:
+		return fmt.Errorf("unknown manifest role: %s", role)
+	}
+}
+
+// ImportManifest verifies the manifest and saves it into vault, so that keygen, sign or regroup can start from it
+func ImportManifest(home, vault string, m *Manifest) error {
+	if err := m.Verify(); err != nil {
+		return err
+	}
+	return m.Save(path.Join(home, vault, manifestFileName))
+}
+
+// LoadImportedManifest loads and verifies the manifest imported into vault
+func LoadImportedManifest(home, vault string) (*Manifest, error) {
+	m, err := LoadManifest(path.Join(home, vault, manifestFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no manifest has been imported into this vault, please run `tss manifest import` first")
+		}
+		return nil, err
+	}
+	if err := m.Verify(); err != nil {
+		return nil, fmt.Errorf("imported manifest is not valid: %v", err)
+	}
+	return m, nil
+}
+
+func LoadNodeKey(home, vault string) (crypto.PrivKey, error) {
+	content, err := ioutil.ReadFile(path.Join(home, vault, "node_key"))
+	if err != nil {
+		return nil, err
+	}
+	return crypto.UnmarshalPrivateKey(content)
+}
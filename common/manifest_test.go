@@ -0,0 +1,317 @@
+package common
+
+import (
+	"crypto/rand"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+type manifestParty struct {
+	moniker string
+	id      string
+	privKey crypto.PrivKey
+}
+
+func newManifestParties(t *testing.T, monikers ...string) []manifestParty {
+	parties := make([]manifestParty, 0, len(monikers))
+	for _, moniker := range monikers {
+		privKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := peer.IDFromPrivateKey(privKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parties = append(parties, manifestParty{moniker: moniker, id: id.Pretty(), privKey: privKey})
+	}
+	return parties
+}
+
+func (p manifestParty) member(role string) ManifestMember {
+	return ManifestMember{Moniker: p.moniker, Id: p.id, Addrs: []string{"/ip4/10.0.0.1/tcp/" + p.moniker}, Role: role}
+}
+
+func (p manifestParty) expectedPeer() string {
+	return fmt.Sprintf("%s@%s", p.moniker, p.id)
+}
+
+// signedManifest lists parties with roles (in the same order) and is signed by all of them
+func signedManifest(t *testing.T, channelId string, threshold, n, newThreshold, newN int, parties []manifestParty, roles []string) *Manifest {
+	m := NewManifest(channelId, threshold, n, newThreshold, newN)
+	for i, p := range parties {
+		if err := m.AddMember(p.member(roles[i])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	signAll(t, m, parties)
+	return m
+}
+
+func signAll(t *testing.T, m *Manifest, parties []manifestParty) {
+	for _, p := range parties {
+		if err := m.Sign(p.privKey); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestManifestVerify(t *testing.T) {
+	parties := newManifestParties(t, "a", "b", "c")
+	outsider := newManifestParties(t, "d")[0]
+	roles := []string{ManifestRoleMember, ManifestRoleMember, ManifestRoleMember}
+
+	for _, c := range []struct {
+		name   string
+		tamper func(m *Manifest)
+		err    string // substring of expected error, empty if manifest is valid
+	}{
+		{"signed by all members", func(m *Manifest) {}, ""},
+		{"saved and loaded", func(m *Manifest) {
+			file := t.TempDir() + "/committee.json"
+			if err := m.Save(file); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := LoadManifest(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			*m = *loaded
+		}, ""},
+		{"missing a signature", func(m *Manifest) {
+			m.Signatures = m.Signatures[:2]
+		}, "has not signed"},
+		{"role changed after signing", func(m *Manifest) {
+			m.Members[2].Role = ManifestRoleSigner
+		}, "invalid manifest signature"},
+		{"address changed after signing", func(m *Manifest) {
+			m.Members[0].Addrs = []string{"/ip4/10.0.0.2/tcp/a"}
+		}, "invalid manifest signature"},
+		{"member removed after signing", func(m *Manifest) {
+			m.Members = m.Members[:2]
+			m.Signatures = m.Signatures[:2]
+		}, "invalid manifest signature"},
+		{"member added after signing", func(m *Manifest) {
+			m.Members = append(m.Members, outsider.member(ManifestRoleMember))
+		}, "invalid manifest signature"},
+		{"signatures swapped", func(m *Manifest) {
+			m.Signatures[0].Signature, m.Signatures[1].Signature = m.Signatures[1].Signature, m.Signatures[0].Signature
+		}, "invalid manifest signature"},
+		{"signed by a non-member", func(m *Manifest) {
+			data, err := m.signingBytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			signature, err := outsider.privKey.Sign(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.Signatures = append(m.Signatures, ManifestSignature{Id: outsider.id, Signature: signature})
+		}, "not a member"},
+		{"channel id changed after signing", func(m *Manifest) {
+			m.ChannelId = newTestChannelId(t, time.Hour)
+		}, "invalid manifest signature"},
+		{"malformed channel id", func(m *Manifest) {
+			m.ChannelId = "not a channel id"
+			signAll(t, m, parties)
+		}, "channelId format is invalid"},
+		{"expired channel id", func(m *Manifest) {
+			m.ChannelId = newTestChannelId(t, -time.Minute)
+			signAll(t, m, parties)
+		}, "expired"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			m := signedManifest(t, newTestChannelId(t, time.Hour), 1, 3, 0, 0, parties, roles)
+			c.tamper(m)
+			err := m.Verify()
+			if c.err == "" && err != nil {
+				t.Fatalf("manifest should be valid: %v", err)
+			}
+			if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+				t.Fatalf("expected error containing %q, got %v", c.err, err)
+			}
+		})
+	}
+
+	m := signedManifest(t, newTestChannelId(t, time.Hour), 1, 3, 0, 0, parties, roles)
+	if err := m.Sign(outsider.privKey); err == nil {
+		t.Fatal("a non-member should not be able to sign")
+	}
+	if err := m.AddMember(outsider.member(ManifestRoleMember)); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Signatures) != 0 {
+		t.Fatal("signatures should be cleared once a member is added")
+	}
+}
+
+func TestManifestApply(t *testing.T) {
+	// a, b and c hold shares of a 2-of-3 scheme, d, e and f are the new committee of regroup
+	parties := newManifestParties(t, "a", "b", "c", "d", "e", "f")
+	a, b, c, d, e, f := parties[0], parties[1], parties[2], parties[3], parties[4], parties[5]
+	channelId := newTestChannelId(t, time.Hour)
+	configOf := func(p manifestParty) *TssConfig {
+		return &TssConfig{Id: TssClientId(p.id), Moniker: p.moniker}
+	}
+	// vault of a after keygen, addresses of peers are the ones used in keygen
+	vaultOfA := func() *TssConfig {
+		config := configOf(a)
+		config.Parties, config.Threshold = 3, 1
+		config.ExpectedPeers = []string{b.expectedPeer(), c.expectedPeer()}
+		config.PeerAddrs = []string{"/ip4/10.0.0.9/tcp/b", "/ip4/10.0.0.9/tcp/c"}
+		return config
+	}
+	addr := func(p manifestParty) string {
+		return p.member("").Addrs[0]
+	}
+
+	for _, tc := range []struct {
+		name     string
+		parties  []manifestParty
+		roles    []string
+		newN     int
+		config   *TssConfig
+		mode     BootstrapMode
+		expected *TssConfig // nil if Apply should fail
+	}{
+		{
+			name:    "keygen",
+			parties: []manifestParty{a, b, c},
+			roles:   []string{ManifestRoleMember, ManifestRoleMember, ManifestRoleMember},
+			config:  configOf(b),
+			mode:    KeygenMode,
+			expected: &TssConfig{
+				P2PConfig: P2PConfig{
+					ExpectedPeers: []string{a.expectedPeer(), c.expectedPeer()},
+					PeerAddrs:     []string{addr(a), addr(c)},
+				},
+				Id: TssClientId(b.id), Moniker: b.moniker, ChannelId: channelId,
+				Parties: 3, Threshold: 1,
+			},
+		},
+		{
+			name:    "keygen with a member missing",
+			parties: []manifestParty{a, b},
+			roles:   []string{ManifestRoleMember, ManifestRoleMember},
+			config:  configOf(b),
+			mode:    KeygenMode,
+		},
+		{
+			name:    "sign",
+			parties: []manifestParty{a, c},
+			roles:   []string{ManifestRoleSigner, ManifestRoleSigner},
+			config:  vaultOfA(),
+			mode:    SignMode,
+			expected: &TssConfig{
+				// only the address of c is updated, b is not a signer
+				P2PConfig: P2PConfig{
+					ExpectedPeers: []string{b.expectedPeer(), c.expectedPeer()},
+					PeerAddrs:     []string{"/ip4/10.0.0.9/tcp/b", addr(c)},
+				},
+				Id: TssClientId(a.id), Moniker: a.moniker, ChannelId: channelId,
+				Parties: 3, Threshold: 1,
+			},
+		},
+		{
+			name:    "sign with a signer outside the vault",
+			parties: []manifestParty{a, d},
+			roles:   []string{ManifestRoleSigner, ManifestRoleSigner},
+			config:  vaultOfA(),
+			mode:    SignMode,
+		},
+		{
+			name:    "regroup of an old party",
+			parties: []manifestParty{a, b, d, e, f},
+			roles:   []string{ManifestRoleOld, ManifestRoleOld, ManifestRoleNew, ManifestRoleNew, ManifestRoleNew},
+			newN:    3,
+			config:  vaultOfA(),
+			mode:    RegroupMode,
+			expected: &TssConfig{
+				P2PConfig: P2PConfig{
+					ExpectedPeers:    []string{b.expectedPeer(), c.expectedPeer()},
+					PeerAddrs:        []string{addr(b), "/ip4/10.0.0.9/tcp/c"},
+					ExpectedNewPeers: []string{d.expectedPeer(), e.expectedPeer(), f.expectedPeer()},
+					NewPeerAddrs:     []string{addr(d), addr(e), addr(f)},
+				},
+				Id: TssClientId(a.id), Moniker: a.moniker, ChannelId: channelId,
+				Parties: 3, Threshold: 1,
+				NewParties: 3, NewThreshold: 1, IsOldCommittee: true,
+			},
+		},
+		{
+			name:    "regroup of a new party",
+			parties: []manifestParty{a, b, d, e, f},
+			roles:   []string{ManifestRoleOld, ManifestRoleOld, ManifestRoleNew, ManifestRoleNew, ManifestRoleNew},
+			newN:    3,
+			config:  configOf(e),
+			mode:    RegroupMode,
+			expected: &TssConfig{
+				P2PConfig: P2PConfig{
+					ExpectedPeers:    []string{a.expectedPeer(), b.expectedPeer()},
+					PeerAddrs:        []string{addr(a), addr(b)},
+					ExpectedNewPeers: []string{d.expectedPeer(), f.expectedPeer()},
+					NewPeerAddrs:     []string{addr(d), addr(f)},
+				},
+				Id: TssClientId(e.id), Moniker: e.moniker, ChannelId: channelId,
+				Parties: 3, Threshold: 1,
+				NewParties: 3, NewThreshold: 1, IsNewCommittee: true,
+			},
+		},
+		{
+			name:    "regroup with too few old parties",
+			parties: []manifestParty{a, d, e, f},
+			roles:   []string{ManifestRoleOld, ManifestRoleNew, ManifestRoleNew, ManifestRoleNew},
+			newN:    3,
+			config:  vaultOfA(),
+			mode:    RegroupMode,
+		},
+		{
+			name:    "party not in manifest",
+			parties: []manifestParty{a, b, c},
+			roles:   []string{ManifestRoleMember, ManifestRoleMember, ManifestRoleMember},
+			config:  configOf(d),
+			mode:    KeygenMode,
+		},
+		{
+			name:    "different channel id",
+			parties: []manifestParty{a, b, c},
+			roles:   []string{ManifestRoleMember, ManifestRoleMember, ManifestRoleMember},
+			config: func() *TssConfig {
+				config := configOf(b)
+				config.ChannelId = newTestChannelId(t, time.Hour)
+				return config
+			}(),
+			mode: KeygenMode,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			newT := 0
+			if tc.newN > 0 {
+				newT = 1
+			}
+			m := signedManifest(t, channelId, 1, 3, newT, tc.newN, tc.parties, tc.roles)
+			if err := m.Verify(); err != nil {
+				t.Fatal(err)
+			}
+			err := m.Apply(tc.config, tc.mode)
+			if tc.expected == nil {
+				if err == nil {
+					t.Fatal("manifest should not be applied")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.config, tc.expected) {
+				t.Fatalf("expected config %+v, got %+v", tc.expected, tc.config)
+			}
+		})
+	}
+}
@@ -39,16 +39,5 @@ type PeerParam struct {
 	ChannelId, Moniker, Msg, Id string
 	N, T, NewN, NewT            int
 	IsOld, IsNew                bool
-}
-
-func NewBootstrapMessage(channelId, channelPassword, addr string, param PeerParam) (*BootstrapMessage, error) {
-	pi, err := Encrypt(channelPassword, param)
-	if err != nil {
-		return nil, err
-	}
-	return &BootstrapMessage{
-		ChannelId: channelId,
-		PeerInfo:  pi,
-		Addr:      addr,
-	}, nil
+	Signers                     []string // sorted monikers chosen to sign, empty if signers are whoever online first
 }
@@ -0,0 +1,126 @@
+package common
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"hash"
+
+	"filippo.io/edwards25519/field"
+	"golang.org/x/crypto/curve25519"
+)
+
+// Password authenticated key exchange used by bootstrap, modeled after CPace (draft-irtf-cfrg-cpace) over X25519
+// Both parties map channel id and channel password to a secret generator G_pw with Elligator 2 (see elligator2.go),
+// exchange X25519(y, G_pw) and derive session keys from X25519(y_a, X25519(y_b, G_pw)). Mapping and scalar
+// multiplication are constant time, so timing doesn't leak the password. Exchanged shares are indistinguishable from
+// random points, so a captured handshake cannot be brute-forced offline, an active attacker can only test one guess
+// per connection
+const (
+	pakeGeneratorDST = "tss-bootstrap-cpace-x25519-generator-v2"
+	pakeSessionDST   = "tss-bootstrap-cpace-x25519-session-v2"
+)
+
+type pakeState struct {
+	channelId string
+	secret    [32]byte
+	share     []byte // X25519(secret, G_pw)
+}
+
+type pakeKeys struct {
+	confirmKey []byte
+	encryptKey []byte
+	ownShare   []byte
+	peerShare  []byte
+}
+
+func newPake(channelId, password string) (*pakeState, error) {
+	generator := pakeGenerator(channelId, password)
+	s := &pakeState{channelId: channelId}
+	if _, err := rand.Read(s.secret[:]); err != nil {
+		return nil, err
+	}
+	var share [32]byte
+	curve25519.ScalarMult(&share, &s.secret, &generator)
+	s.share = share[:]
+	return s, nil
+}
+
+// finish derives session keys from peer's share
+func (s *pakeState) finish(peerShare []byte) (*pakeKeys, error) {
+	if len(peerShare) != 32 {
+		return nil, fmt.Errorf("invalid pake share of peer: %d bytes", len(peerShare))
+	}
+	// a reflected share would make both confirmation tags equal
+	if bytes.Equal(peerShare, s.share) {
+		return nil, fmt.Errorf("peer reflected our pake share")
+	}
+	var peer, shared [32]byte
+	copy(peer[:], peerShare)
+	curve25519.ScalarMult(&shared, &s.secret, &peer)
+	// a share of low order gives a shared secret known to the peer without the password
+	if subtle.ConstantTimeCompare(shared[:], make([]byte, 32)) == 1 {
+		return nil, fmt.Errorf("invalid pake share of peer: shared secret is zero")
+	}
+
+	// shares are ordered so that both sides get the same transcript
+	first, second := s.share, peerShare
+	if bytes.Compare(first, second) > 0 {
+		first, second = second, first
+	}
+	h := sha256.New()
+	writeWithLength(h, []byte(pakeSessionDST))
+	writeWithLength(h, []byte(s.channelId))
+	writeWithLength(h, shared[:])
+	writeWithLength(h, first)
+	writeWithLength(h, second)
+	isk := h.Sum(nil)
+
+	return &pakeKeys{
+		confirmKey: hmacSha256(isk, []byte("confirm")),
+		encryptKey: hmacSha256(isk, []byte("encrypt")),
+		ownShare:   s.share,
+		peerShare:  peerShare,
+	}, nil
+}
+
+// tag proves we derived the same session key, it is bound to the direction so it cannot be reflected
+func (k *pakeKeys) ownTag() []byte {
+	return hmacSha256(k.confirmKey, k.ownShare, k.peerShare)
+}
+
+func (k *pakeKeys) verifyPeerTag(tag []byte) bool {
+	return hmac.Equal(tag, hmacSha256(k.confirmKey, k.peerShare, k.ownShare))
+}
+
+// pakeGenerator maps (channel id, password) to a point with unknown discrete logarithm, in constant time
+func pakeGenerator(channelId, password string) [32]byte {
+	h := sha512.New()
+	writeWithLength(h, []byte(pakeGeneratorDST))
+	writeWithLength(h, []byte(channelId))
+	writeWithLength(h, []byte(password))
+	u, err := new(field.Element).SetBytes(h.Sum(nil)[:32])
+	if err != nil {
+		// SHA-512 always gives enough bytes
+		panic(err)
+	}
+	return elligator2(u)
+}
+
+func writeWithLength(h hash.Hash, data []byte) {
+	binary.Write(h, binary.BigEndian, uint32(len(data)))
+	h.Write(data)
+}
+
+func hmacSha256(key []byte, data ...[]byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
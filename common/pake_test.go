@@ -0,0 +1,167 @@
+package common
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/edwards25519/field"
+)
+
+// elligator2Reference is the textbook Elligator 2 over math/big: x1 = -J / (1 + 2u^2), x1 if g(x1) is square, else -x1 - J
+func elligator2Reference(u *big.Int) *big.Int {
+	p := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+	j := big.NewInt(486662)
+	d := new(big.Int).Mul(u, u)
+	d.Lsh(d, 1).Add(d, big.NewInt(1)).Mod(d, p)
+	x1 := new(big.Int).ModInverse(d, p)
+	x1.Mul(x1, j).Neg(x1).Mod(x1, p)
+	// g(x) = x^3 + J*x^2 + x
+	g := new(big.Int).Add(x1, j)
+	g.Mul(g, x1).Add(g, big.NewInt(1)).Mul(g, x1).Mod(g, p)
+	if big.Jacobi(g, p) >= 0 {
+		return x1
+	}
+	x2 := new(big.Int).Neg(x1)
+	x2.Sub(x2, j).Mod(x2, p)
+	return x2
+}
+
+func TestElligator2MatchesReference(t *testing.T) {
+	inputs := [][]byte{make([]byte, 32), bytes.Repeat([]byte{0xff}, 32)}
+	for i := 0; i < 200; i++ {
+		input := make([]byte, 32)
+		if _, err := rand.Read(input); err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, input)
+	}
+	for _, input := range inputs {
+		u, err := new(field.Element).SetBytes(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := elligator2(u)
+
+		be := make([]byte, 32)
+		for i, b := range input {
+			be[31-i] = b
+		}
+		be[0] &= 0x7f
+		want := elligator2Reference(new(big.Int).SetBytes(be)).Bytes()
+		wantLE := make([]byte, 32)
+		for i, b := range want {
+			wantLE[len(want)-1-i] = b
+		}
+		if !bytes.Equal(got[:], wantLE) {
+			t.Fatalf("elligator2(%x) = %x, want %x", input, got, wantLE)
+		}
+	}
+}
+
+func newTestBootstrapper(channelId, password, moniker string) *Bootstrapper {
+	return &Bootstrapper{
+		ChannelId:       channelId,
+		ChannelPassword: password,
+		ExpectedPeers:   1,
+		Param:           PeerParam{ChannelId: channelId, Moniker: moniker, Id: moniker, N: 2, T: 1, IsOld: true},
+		Cfg:             &TssConfig{Moniker: moniker, Parties: 2, Threshold: 1, IsOldCommittee: true},
+		started:         time.Now(),
+	}
+}
+
+func newTestChannelId(t *testing.T, expire time.Duration) string {
+	channelId, err := NewChannelId(time.Now().Add(expire).Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return channelId
+}
+
+// handshake runs handshake of a and b over a loopback tcp connection, frames of both sides are buffered by kernel
+func handshake(t *testing.T, a, b *Bootstrapper) (errA, errB error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	accepted := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			accepted <- err
+			return
+		}
+		defer conn.Close()
+		accepted <- b.Handshake(conn, conn.LocalAddr().String(), conn.RemoteAddr().String())
+	}()
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Minute))
+	errA = a.Handshake(conn, conn.LocalAddr().String(), conn.RemoteAddr().String())
+	return errA, <-accepted
+}
+
+func TestHandshakeWithMatchingPasswords(t *testing.T) {
+	channelId := newTestChannelId(t, time.Hour)
+	a := newTestBootstrapper(channelId, "password", "a")
+	b := newTestBootstrapper(channelId, "password", "b")
+	errA, errB := handshake(t, a, b)
+	if errA != nil || errB != nil {
+		t.Fatalf("handshake failed: %v, %v", errA, errB)
+	}
+	if info, ok := a.Peers.Load("b"); !ok || info.(PeerInfo).Moniker != "b" {
+		t.Fatal("a should learn b")
+	}
+	if info, ok := b.Peers.Load("a"); !ok || info.(PeerInfo).Moniker != "a" {
+		t.Fatal("b should learn a")
+	}
+}
+
+func TestHandshakeRejectsWrongPassword(t *testing.T) {
+	channelId := newTestChannelId(t, time.Hour)
+	a := newTestBootstrapper(channelId, "password", "a")
+	b := newTestBootstrapper(channelId, "passwore", "b")
+	errA, errB := handshake(t, a, b)
+	for _, err := range []error{errA, errB} {
+		if _, ok := err.(*BootstrapRejectedError); !ok || !strings.Contains(err.Error(), "different channel password") {
+			t.Fatalf("handshake should be rejected for a different channel password, got: %v", err)
+		}
+	}
+	if a.LenOfPeers() != 0 || b.LenOfPeers() != 0 {
+		t.Fatal("peers should not be learned with a wrong password")
+	}
+}
+
+func TestHandshakeWithExpiredChannelId(t *testing.T) {
+	channelId := newTestChannelId(t, -time.Minute)
+	a := newTestBootstrapper(channelId, "password", "a")
+	var buf bytes.Buffer
+	err := a.Handshake(&buf, "", "b")
+	if err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Fatalf("handshake should fail for an expired channel id, got: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatal("nothing should be sent with an expired channel id")
+	}
+}
+
+func TestPakeRejectsLowOrderShare(t *testing.T) {
+	pake, err := newPake("channel", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 0 and 1 are of small order, X25519 of them is 0 whatever the secret is
+	for _, share := range [][]byte{make([]byte, 32), append([]byte{1}, make([]byte, 31)...)} {
+		if _, err := pake.finish(share); err == nil {
+			t.Fatalf("share %x should be rejected", share)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package common
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bnb-chain/tss-lib/v2/ecdsa/keygen"
+)
+
+const (
+	preParamsDir        = "preparams"
+	preParamsExt        = ".json"
+	preParamsTakenExt   = ".taken"
+	preParamsWritingExt = ".writing"
+	preParamsInvalidExt = ".invalid"
+)
+
+// Pool of pre-generated Paillier keys and safe primes (keygen.LocalPreParams)
+// Each pre params is encrypted by vault passphrase and saved as an individual file under <home>/<vault>/preparams/
+// A pre params must only be used once, so it is removed from pool once it is taken
+
+// regroup child process uses a temporary vault, while the pool is filled for the original one
+func preParamsPath(home, vault string) string {
+	return path.Join(home, strings.TrimSuffix(vault, RegroupSuffix), preParamsDir)
+}
+
+func SavePreParams(home, vault string, preParams *keygen.LocalPreParams, config KDFConfig, passphrase string) error {
+	dir := preParamsPath(home, vault)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	plainText, err := json.Marshal(preParams)
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	// file names are sortable by creation time, so that oldest pre params are consumed first
+	name := fmt.Sprintf("%d_%s", time.Now().UnixNano(), hex.EncodeToString(suffix))
+
+	// write to a temporary file and rename so that a half written file would never be taken
+	writing := path.Join(dir, name+preParamsWritingExt)
+	w, err := os.OpenFile(writing, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if err = encryptAndWrite(plainText, config, passphrase, w); err != nil {
+		w.Close()
+		os.Remove(writing)
+		return err
+	}
+	if err = w.Close(); err != nil {
+		os.Remove(writing)
+		return err
+	}
+	return os.Rename(writing, path.Join(dir, name+preParamsExt))
+}
+
+// TakePreParams removes the oldest pre params from pool and returns it
+// returns nil without error if the pool is empty
+func TakePreParams(home, vault, passphrase string) (*keygen.LocalPreParams, error) {
+	dir := preParamsPath(home, vault)
+	for _, name := range listPreParams(dir) {
+		available := path.Join(dir, name)
+		taken := strings.TrimSuffix(available, preParamsExt) + preParamsTakenExt
+		// rename is atomic, if another process has taken this one, try next
+		if err := os.Rename(available, taken); err != nil {
+			continue
+		}
+
+		r, err := os.Open(taken)
+		if err != nil {
+			os.Rename(taken, available)
+			return nil, err
+		}
+		plainText, err := readAndDecrypt(r, passphrase)
+		r.Close()
+		if err != nil {
+			// i.e. a mistyped passphrase, pre params took minutes to generate so they go back to pool
+			os.Rename(taken, available)
+			return nil, fmt.Errorf("failed to decrypt pre params %s: %v", name, err)
+		}
+
+		var preParams keygen.LocalPreParams
+		if err := json.Unmarshal(plainText, &preParams); err != nil {
+			os.Rename(taken, available)
+			return nil, fmt.Errorf("failed to unmarshal pre params %s: %v", name, err)
+		}
+		if !preParams.ValidateWithProof() {
+			// kept aside rather than removed, so that nothing is lost if validation is wrong
+			logger.Warningf("pre params %s is not valid (might be generated by an older version), skip it", name)
+			os.Rename(taken, strings.TrimSuffix(available, preParamsExt)+preParamsInvalidExt)
+			continue
+		}
+		// only removed once it is decoded and validated
+		os.Remove(taken)
+		return &preParams, nil
+	}
+	return nil, nil
+}
+
+// NumOfPreParams returns how many unused pre params are in the pool
+func NumOfPreParams(home, vault string) int {
+	return len(listPreParams(preParamsPath(home, vault)))
+}
+
+func listPreParams(dir string) []string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), preParamsExt) {
+			names = append(names, f.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
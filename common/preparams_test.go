@@ -0,0 +1,35 @@
+package common
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/bnb-chain/tss-lib/v2/ecdsa/keygen"
+)
+
+func TestTakePreParamsKeepsPoolOnWrongPassphrase(t *testing.T) {
+	home := t.TempDir()
+	// not valid pre params, but decoding doesn't care
+	preParams := &keygen.LocalPreParams{NTildei: big.NewInt(1), H1i: big.NewInt(2), H2i: big.NewInt(3)}
+	if err := SavePreParams(home, "v", preParams, DefaultKDFConfig(), "right"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := TakePreParams(home, "v", "wrong"); err == nil {
+		t.Fatal("pre params should not be decrypted with a wrong passphrase")
+	}
+	if n := NumOfPreParams(home, "v"); n != 1 {
+		t.Fatalf("pre params should be back to pool after a wrong passphrase, %d left", n)
+	}
+
+	taken, err := TakePreParams(home, "v", "right")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if taken != nil {
+		t.Fatal("invalid pre params should not be taken")
+	}
+	if n := NumOfPreParams(home, "v"); n != 0 {
+		t.Fatalf("invalid pre params should be put aside, %d left", n)
+	}
+}
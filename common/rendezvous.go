@@ -0,0 +1,131 @@
+package common
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	RendezvousPath    = "/rendezvous/"
+	rendezvousSaltDST = "tss-rendezvous-v1"
+	rendezvousTimeout = 10 * time.Second
+)
+
+// RendezvousMessage is what a party registers on rendezvous server, encrypted by a key derived from channel id and password
+type RendezvousMessage struct {
+	Moniker string
+	Addrs   []string // multiaddrs this party's bootstrap listener can be connected
+}
+
+type rendezvousEntry struct {
+	Registrant string `json:"registrant"`
+	Payload    []byte `json:"payload"`
+}
+
+// RendezvousClient registers our bootstrap message on a rendezvous server (see `tss server`) and discovers others'
+// It only helps parties find each other's address, peers still authenticate each other via Bootstrapper.Handshake
+type RendezvousClient struct {
+	server     string
+	topic      string
+	registrant string
+	key        []byte
+	client     *http.Client
+}
+
+// server is the base url of rendezvous server, i.e. http://127.0.0.1:27149
+func NewRendezvousClient(server, channelId, channelPassword string) (*RendezvousClient, error) {
+	if err := ValidateChannelId(channelId); err != nil {
+		return nil, err
+	}
+	registrant := make([]byte, 16)
+	if _, err := rand.Read(registrant); err != nil {
+		return nil, err
+	}
+
+	// the rendezvous server can brute-force channel password against registered messages offline, see DeriveChannelKey
+	master := DeriveChannelKey(channelId, channelPassword, rendezvousSaltDST)
+	return &RendezvousClient{
+		server:     strings.TrimSuffix(server, "/"),
+		topic:      hex.EncodeToString(hmacSha256(master, []byte("topic"))),
+		registrant: hex.EncodeToString(registrant),
+		key:        hmacSha256(master, []byte("encrypt")),
+		client:     &http.Client{Timeout: rendezvousTimeout},
+	}, nil
+}
+
+// Register publishes (or refreshes) our message
+func (c *RendezvousClient) Register(msg *RendezvousMessage) error {
+	plaintext, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	payload, err := encryptWithKey(c.key, plaintext)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, c.server+RendezvousPath+c.topic+"/"+c.registrant, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to register on rendezvous server: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to register on rendezvous server: %s", readRendezvousError(resp.Body, resp.Status))
+	}
+	return nil
+}
+
+// Discover returns messages registered by other parties of this channel
+func (c *RendezvousClient) Discover() ([]*RendezvousMessage, error) {
+	resp, err := c.client.Get(c.server + RendezvousPath + c.topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rendezvous server: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to query rendezvous server: %s", readRendezvousError(resp.Body, resp.Status))
+	}
+
+	var entries []rendezvousEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to parse rendezvous response: %v", err)
+	}
+	msgs := make([]*RendezvousMessage, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Registrant == c.registrant {
+			continue
+		}
+		plaintext, err := decryptWithKey(c.key, entry.Payload)
+		if err != nil {
+			// topic is derived from password as well, so this is not a peer with wrong password but a forged entry
+			logger.Warningf("skip undecryptable rendezvous entry %s: %v", entry.Registrant, err)
+			continue
+		}
+		var msg RendezvousMessage
+		if err := json.Unmarshal(plaintext, &msg); err != nil {
+			logger.Warningf("skip malformed rendezvous entry %s: %v", entry.Registrant, err)
+			continue
+		}
+		msgs = append(msgs, &msg)
+	}
+	return msgs, nil
+}
+
+func readRendezvousError(body io.Reader, status string) string {
+	msg, _ := ioutil.ReadAll(io.LimitReader(body, 512))
+	if reason := strings.TrimSpace(string(msg)); reason != "" {
+		return fmt.Sprintf("%s, %s", status, reason)
+	}
+	return status
+}
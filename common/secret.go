@@ -0,0 +1,172 @@
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bgentry/speakeasy"
+)
+
+const (
+	SecretSourcePrompt  = "prompt"
+	SecretSourceFile    = "file"
+	SecretSourceFd      = "fd"
+	SecretSourceEnv     = "env"
+	SecretSourceAskpass = "askpass"
+)
+
+// SecretProvider supplies passphrases and channel passwords without putting them on the command line
+// A source is described as "<kind>:<argument>", i.e.
+//
+//	prompt              - ask on the terminal (default when source is empty)
+//	file:/path/to/file  - first line of the file
+//	fd:3                - next line of an inherited file descriptor, successive reads consume successive lines
+//	env:TSS_PASSWORD    - value of the environment variable
+//	askpass:/usr/bin/x  - stdout of an external program invoked with the prompt as its only argument
+type SecretProvider interface {
+	Secret(prompt string) (string, error)
+	Interactive() bool // whether the secret is typed by a human, so that we can ask to repeat it
+}
+
+func NewSecretProvider(source string) (SecretProvider, error) {
+	if source == "" || source == SecretSourcePrompt {
+		return promptProvider{}, nil
+	}
+	parts := strings.SplitN(source, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("invalid secret source %q, should be in <kind>:<argument> format", source)
+	}
+	switch parts[0] {
+	case SecretSourceFile:
+		return fileProvider{parts[1]}, nil
+	case SecretSourceFd:
+		fd, err := strconv.Atoi(parts[1])
+		if err != nil || fd < 0 {
+			return nil, fmt.Errorf("invalid file descriptor in secret source %q", source)
+		}
+		return fdProvider{uintptr(fd)}, nil
+	case SecretSourceEnv:
+		return envProvider{parts[1]}, nil
+	case SecretSourceAskpass:
+		return askpassProvider{parts[1]}, nil
+	default:
+		return nil, fmt.Errorf("unknown secret source kind: %s", parts[0])
+	}
+}
+
+// ReadSecret reads a secret from source, prompt is shown to the user (or passed to askpass program)
+func ReadSecret(source, prompt string) (string, error) {
+	provider, err := NewSecretProvider(source)
+	if err != nil {
+		return "", err
+	}
+	return provider.Secret(prompt)
+}
+
+type promptProvider struct{}
+
+func (promptProvider) Secret(prompt string) (string, error) {
+	return speakeasy.Ask(fmt.Sprintf("> %s:", prompt))
+}
+
+func (promptProvider) Interactive() bool {
+	return true
+}
+
+type fileProvider struct {
+	path string
+}
+
+func (p fileProvider) Secret(_ string) (string, error) {
+	content, err := ioutil.ReadFile(p.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret from file: %v", err)
+	}
+	return firstLine(content), nil
+}
+
+func (fileProvider) Interactive() bool {
+	return false
+}
+
+var (
+	fdReadersMtx sync.Mutex
+	fdReaders    = make(map[uintptr]*bufio.Reader) // shared so that several secrets can be passed through one pipe
+)
+
+type fdProvider struct {
+	fd uintptr
+}
+
+func (p fdProvider) Secret(_ string) (string, error) {
+	fdReadersMtx.Lock()
+	defer fdReadersMtx.Unlock()
+
+	reader, ok := fdReaders[p.fd]
+	if !ok {
+		f := os.NewFile(p.fd, fmt.Sprintf("secret-fd-%d", p.fd))
+		if f == nil {
+			return "", fmt.Errorf("file descriptor %d is not valid", p.fd)
+		}
+		reader = bufio.NewReader(f)
+		fdReaders[p.fd] = reader
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read secret from file descriptor %d: %v", p.fd, err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (fdProvider) Interactive() bool {
+	return false
+}
+
+type envProvider struct {
+	name string
+}
+
+func (p envProvider) Secret(_ string) (string, error) {
+	value, ok := os.LookupEnv(p.name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", p.name)
+	}
+	return value, nil
+}
+
+func (envProvider) Interactive() bool {
+	return false
+}
+
+type askpassProvider struct {
+	program string
+}
+
+func (p askpassProvider) Secret(prompt string) (string, error) {
+	askpass := exec.Command(p.program, prompt)
+	askpass.Stdin = os.Stdin
+	askpass.Stderr = os.Stderr
+	out, err := askpass.Output()
+	if err != nil {
+		return "", fmt.Errorf("askpass program %s failed: %v", p.program, err)
+	}
+	return firstLine(out), nil
+}
+
+func (askpassProvider) Interactive() bool {
+	return false
+}
+
+func firstLine(content []byte) string {
+	if idx := bytes.IndexByte(content, '\n'); idx >= 0 {
+		content = content[:idx]
+	}
+	return strings.TrimRight(string(content), "\r")
+}
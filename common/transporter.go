@@ -1,16 +1,28 @@
 package common
 
 import (
+	"time"
+
 	"github.com/bnb-chain/tss-lib/v2/tss"
 )
 
 // Transportation layer of TssClient provide Broadcast and Send method over p2p network
 // ReceiveCh() provides msgs this client received
-// TODO: consider a ControlCh() to expose ready&err msgs to application?
+// ControlCh() reports how messages sent are delivered to peers
 type Transporter interface {
 	NodeKey() []byte // return party's p2p private key, encryption it together with keygen secret so that when move party to other machine, we only copy encrypted file
 	Broadcast(msg tss.Message) error
-	Send(msg []byte, to TssClientId) error // msg is result of proto.Marshal prepended by 0x01 - protob.Message, 0x02 - P2PMessageWithHash
-	ReceiveCh() <-chan P2pMessageWrapper   // messages have received !consumer of this channel should not taking too long!
+	Send(msg []byte, to TssClientId) error       // msg is result of proto.Marshal prepended by a type prefix, i.e. 0x01 - tss.MessageWrapper (see p2p.MessagePrefix for the others)
+	ReceiveCh() <-chan P2pMessageWrapper         // messages have received !consumer of this channel should not taking too long!
+	ControlCh() <-chan ControlMessage            // delivery acknowledgements and errors of peers
+	WaitForDelivery(timeout time.Duration) error // blocks until messages sent so far are acknowledged by peers (who haven't finished)
 	Shutdown() error
 }
+
+// ControlMessage is either an acknowledgement or an error of a peer.
+// Acknowledgements are cumulative, so they might be skipped if the consumer is slow, errors are never skipped
+type ControlMessage struct {
+	Peer      TssClientId
+	Delivered uint64 // how many messages sent to peer are acknowledged
+	Err       error  // peer cannot be reached anymore (messages to it are lost), or it sent what cannot be trusted (it is dropped)
+}
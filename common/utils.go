@@ -6,22 +6,21 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
 	"io"
 	"math"
+	"net"
 	"os"
-	"regexp"
 	"runtime/debug"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/ipfs/go-log"
 	"github.com/mattn/go-isatty"
+	"github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
 )
 
 var logger = log.Logger("common")
@@ -30,12 +29,10 @@ const (
 	RegroupSuffix = "_rgtmp"
 )
 
-func Encrypt(passphrase string, param PeerParam) ([]byte, error) {
-	text, err := json.Marshal(param)
-	key := sha256.Sum256([]byte(passphrase))
-
+// encryptWithKey encrypts plaintext with AES-256-GCM, nonce is prepended to the result
+func encryptWithKey(key, plaintext []byte) ([]byte, error) {
 	// generate a new aes cipher using our 32 byte long key
-	c, err := aes.NewCipher(key[:])
+	c, err := aes.NewCipher(key)
 	// if there are any errors, handle them
 	if err != nil {
 		return nil, err
@@ -65,50 +62,27 @@ func Encrypt(passphrase string, param PeerParam) ([]byte, error) {
 	// additional data and appends the result to dst, returning the updated
 	// slice. The nonce must be NonceSize() bytes long and unique for all
 	// time, for a given key.
-	return gcm.Seal(nonce, nonce, text, nil), nil
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
 }
 
-func Decrypt(ciphertext []byte, channelId, passphrase string) (param *PeerParam, error error) {
-	key := sha256.Sum256([]byte(passphrase))
-	c, err := aes.NewCipher(key[:])
+func decryptWithKey(key, ciphertext []byte) ([]byte, error) {
+	c, err := aes.NewCipher(key)
 	if err != nil {
-		error = err
-		return
+		return nil, err
 	}
 
 	gcm, err := cipher.NewGCM(c)
 	if err != nil {
-		error = err
-		return
+		return nil, err
 	}
 
 	nonceSize := gcm.NonceSize()
 	if len(ciphertext) < nonceSize {
-		error = fmt.Errorf("ciphertext is not as long as expected")
-		return
+		return nil, fmt.Errorf("ciphertext is not as long as expected")
 	}
 
 	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
-		error = err
-		return
-	}
-
-	param = &PeerParam{}
-	error = json.Unmarshal(plaintext, param)
-	if error != nil {
-		return
-	}
-	if param.ChannelId != channelId {
-		error = fmt.Errorf("wrong channel id of message")
-		return
-	}
-	epochSeconds := ConvertHexToTimestamp(channelId[3:])
-	if time.Now().Unix() > int64(epochSeconds) {
-		Panic(fmt.Errorf("channel id has been expired, please regenerate a new one"))
-	}
-	return param, nil
+	return gcm.Open(nil, nonce, ciphertext, nil)
 }
 
 // conversion between hex and int32 epoch seconds
@@ -133,18 +107,67 @@ func ConvertHexToTimestamp(hexTimestamp string) int {
 	return int(epochSeconds)
 }
 
+// ReplaceIpInAddr replaces ip4 or ip6 host of multiaddr addr with realIp, addr is returned as is if it is not an ip multiaddr
 func ReplaceIpInAddr(addr, realIp string) string {
-	re := regexp.MustCompile(`((([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])\.){3}([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5]))\/tcp`)
-	return re.ReplaceAllString(addr, realIp+"/tcp")
+	maddr, err := multiaddr.NewMultiaddr(strings.TrimSpace(addr))
+	if err != nil {
+		return addr
+	}
+	ip := net.ParseIP(realIp)
+	if ip == nil {
+		return addr
+	}
+	host, err := manet.FromIP(ip)
+	if err != nil {
+		return addr
+	}
+	first, rest := multiaddr.SplitFirst(maddr)
+	if first == nil || (first.Protocol().Code != multiaddr.P_IP4 && first.Protocol().Code != multiaddr.P_IP6) {
+		return addr
+	}
+	if rest == nil {
+		return host.String()
+	}
+	return host.Encapsulate(rest).String()
 }
 
+// ConvertMultiAddrStrToNormalAddr converts /ip4|ip6|dns4|dns6/<host>/tcp/<port>[/...] into host:port accepted by net.Dial and net.Listen
 func ConvertMultiAddrStrToNormalAddr(listenAddr string) (string, error) {
-	re := regexp.MustCompile(`((([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])\.){3}([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5]))\/tcp\/([0-9]+)`)
-	all := re.FindStringSubmatch(listenAddr)
-	if len(all) != 6 {
-		return "", fmt.Errorf("failed to convert multiaddr to listen addr")
+	maddr, err := multiaddr.NewMultiaddr(strings.TrimSpace(listenAddr))
+	if err != nil {
+		return "", fmt.Errorf("failed to convert multiaddr to listen addr: %v", err)
+	}
+	network, addr, err := manet.DialArgs(maddr)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert multiaddr to listen addr: %v", err)
+	}
+	if network != "tcp4" && network != "tcp6" {
+		return "", fmt.Errorf("failed to convert multiaddr to listen addr: %s is not a tcp address", listenAddr)
+	}
+	return addr, nil
+}
+
+// IsLoopbackAddr reports whether multiaddr addr is on 127.0.0.0/8 or ::1, such address is only used when it is the only choice
+func IsLoopbackAddr(addr string) bool {
+	maddr, err := multiaddr.NewMultiaddr(strings.TrimSpace(addr))
+	return err == nil && manet.IsIPLoopback(maddr)
+}
+
+// IsUnspecifiedAddr reports whether multiaddr addr is on 0.0.0.0 or ::, which cannot be connected by peers
+func IsUnspecifiedAddr(addr string) bool {
+	maddr, err := multiaddr.NewMultiaddr(strings.TrimSpace(addr))
+	return err == nil && manet.IsIPUnspecified(maddr)
+}
+
+// LoopbackIfUnspecified replaces 0.0.0.0 (::) in multiaddr addr with 127.0.0.1 (::1)
+func LoopbackIfUnspecified(addr string) string {
+	if !IsUnspecifiedAddr(addr) {
+		return addr
+	}
+	if strings.HasPrefix(strings.TrimSpace(addr), "/ip6/") {
+		return ReplaceIpInAddr(addr, net.IPv6loopback.String())
 	}
-	return fmt.Sprintf("%s:%s", all[1], all[5]), nil
+	return ReplaceIpInAddr(addr, "127.0.0.1")
 }
 
 func GetInt(prompt string, defaultValue int, buf *bufio.Reader) (int, error) {
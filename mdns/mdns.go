@@ -0,0 +1,275 @@
+package mdns
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/ipv4"
+
+	"github.com/bnb-chain/tss/client"
+	"github.com/bnb-chain/tss/common"
+)
+
+const (
+	mdnsGroupAddr    = "224.0.0.251:5353"
+	serviceName      = "_tss._tcp.local."
+	mdnsKeyDST       = "tss-mdns-v1"
+	mdnsTTL          = 120
+	announceInterval = 500 * time.Millisecond
+	txtChannelKey    = "ch="
+	txtAddrKey       = "addr="
+)
+
+// MdnsService is the mDNS/DNS-SD counterpart of ssdp.SsdpService, for networks blocking ssdp but allowing mDNS
+// Every party keeps announcing a PTR record (_tss._tcp.local. -> <usn>._tss._tcp.local.) and a TXT record
+// (channel tag and listen addresses) of its instance. Like ssdp, channel tag and usn are keyed hashes derived
+// from channel id and channel password, so that parties of other channels are ignored and monikers are not exposed
+// Peers are reported to found callback of CollectPeerAddrs and recorded in PeerAddrs
+type MdnsService struct {
+	listenAddrs   []string
+	expectedPeers int                 // how many listen addresses should be collected before exist
+	existingUsns  map[string]struct{} // usns of existing monikers used to filter out already known listen_addr, this used to exclude already known address during regroup
+	channelTag    string
+	usn           string
+	ifaces        []net.Interface
+	connMtx       sync.Mutex // guards conn, which is set by CollectPeerAddrs and closed by Close
+	conn          *net.UDPConn
+	found         func(peerAddr string)
+	closed        chan struct{} // closed when announcing should stop
+	closeOnce     sync.Once
+
+	PeerAddrs sync.Map // map[string]string (usn -> connectable address)
+}
+
+var _ common.Discovery = (*MdnsService)(nil)
+
+// listenAddrs - comma separated listen address
+// ifaces - network interfaces to announce and listen on, see common.MulticastInterfaces
+func NewMdnsService(moniker, channelId, channelPassword, listenAddrs string, expectedPeers int, existingMonikers map[string]struct{}, ifaces []net.Interface) *MdnsService {
+	key := common.DeriveChannelKey(channelId, channelPassword, mdnsKeyDST)
+	s := &MdnsService{
+		expectedPeers: expectedPeers,
+		existingUsns:  make(map[string]struct{}),
+		channelTag:    common.ChannelTag(key, "service"),
+		usn:           usnOf(key, moniker),
+		ifaces:        ifaces,
+		closed:        make(chan struct{}),
+	}
+	for _, addr := range strings.Split(listenAddrs, ",") {
+		s.listenAddrs = append(s.listenAddrs, strings.TrimSpace(addr))
+	}
+	for moniker := range existingMonikers {
+		s.existingUsns[usnOf(key, moniker)] = struct{}{}
+	}
+	return s
+}
+
+func (s *MdnsService) CollectPeerAddrs(found func(peerAddr string)) error {
+	group, err := net.ResolveUDPAddr("udp4", mdnsGroupAddr)
+	if err != nil {
+		return err
+	}
+	// listening on group address (rather than 0.0.0.0) makes the port shareable with other mDNS responders on this host
+	conn, err := net.ListenUDP("udp4", group)
+	if err != nil {
+		return fmt.Errorf("failed to listen mdns: %v", err)
+	}
+	s.connMtx.Lock()
+	select {
+	case <-s.closed:
+		// closed before we started listening, nobody else would close conn
+		s.connMtx.Unlock()
+		return conn.Close()
+	default:
+	}
+	s.conn = conn
+	s.connMtx.Unlock()
+	s.found = found
+	pconn := ipv4.NewPacketConn(conn)
+	if err := pconn.SetMulticastLoopback(true); err != nil {
+		return err
+	}
+	var ifaces []net.Interface
+	for _, ifi := range s.ifaces {
+		if err := pconn.JoinGroup(&ifi, group); err != nil {
+			client.Logger.Warningf("failed to join mdns group on %s: %v", ifi.Name, err)
+			continue
+		}
+		ifaces = append(ifaces, ifi)
+	}
+	if len(ifaces) == 0 {
+		return fmt.Errorf("failed to join mdns group on any network interface")
+	}
+
+	announcement, err := s.buildAnnouncement()
+	if err != nil {
+		return err
+	}
+	if err := announce(pconn, ifaces, group, announcement); err != nil {
+		return err
+	}
+	go s.announceRoutine(pconn, ifaces, group, announcement)
+
+	buf := make([]byte, 9000)
+	for {
+		n, _, _, err := pconn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.closed:
+				// bootstrapping finished as peers found us earlier than we found them
+				return nil
+			default:
+				return fmt.Errorf("failed to receive mdns packet: %v", err)
+			}
+		}
+		if s.handlePacket(buf[:n]) {
+			return nil
+		}
+	}
+}
+
+func (s *MdnsService) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.connMtx.Lock()
+		defer s.connMtx.Unlock()
+		close(s.closed)
+		if s.conn != nil {
+			err = s.conn.Close()
+		}
+	})
+	return err
+}
+
+func (s *MdnsService) announceRoutine(pconn *ipv4.PacketConn, ifaces []net.Interface, group *net.UDPAddr, announcement []byte) {
+	ticker := time.NewTicker(announceInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.closed:
+			return
+		case <-ticker.C:
+			if err := announce(pconn, ifaces, group, announcement); err != nil {
+				client.Logger.Warning(err)
+			}
+		}
+	}
+}
+
+func announce(pconn *ipv4.PacketConn, ifaces []net.Interface, group *net.UDPAddr, announcement []byte) error {
+	for _, ifi := range ifaces {
+		if err := pconn.SetMulticastInterface(&ifi); err != nil {
+			return err
+		}
+		if _, err := pconn.WriteTo(announcement, nil, group); err != nil {
+			return fmt.Errorf("failed to announce via mdns on %s: %v", ifi.Name, err)
+		}
+	}
+	return nil
+}
+
+// buildAnnouncement builds an unsolicited mDNS response with PTR and TXT record of our instance
+func (s *MdnsService) buildAnnouncement() ([]byte, error) {
+	service, err := dnsmessage.NewName(serviceName)
+	if err != nil {
+		return nil, err
+	}
+	instance, err := dnsmessage.NewName(s.usn + "." + serviceName)
+	if err != nil {
+		return nil, err
+	}
+	txt := []string{txtChannelKey + s.channelTag}
+	for _, addr := range s.listenAddrs {
+		txt = append(txt, txtAddrKey+addr)
+	}
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, Authoritative: true})
+	if err := builder.StartAnswers(); err != nil {
+		return nil, err
+	}
+	if err := builder.PTRResource(
+		dnsmessage.ResourceHeader{Name: service, Class: dnsmessage.ClassINET, TTL: mdnsTTL},
+		dnsmessage.PTRResource{PTR: instance}); err != nil {
+		return nil, err
+	}
+	if err := builder.TXTResource(
+		dnsmessage.ResourceHeader{Name: instance, Class: dnsmessage.ClassINET, TTL: mdnsTTL},
+		dnsmessage.TXTResource{TXT: txt}); err != nil {
+		return nil, err
+	}
+	return builder.Finish()
+}
+
+// handlePacket records peer's listen address in a announcement, returns whether we have found all expected peers
+func (s *MdnsService) handlePacket(packet []byte) bool {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(packet)
+	if err != nil || !header.Response {
+		return false
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return false
+	}
+	answers, err := parser.AllAnswers()
+	if err != nil {
+		return false
+	}
+	for _, answer := range answers {
+		txt, ok := answer.Body.(*dnsmessage.TXTResource)
+		if !ok || !strings.HasSuffix(answer.Header.Name.String(), "."+serviceName) {
+			continue
+		}
+		usn := strings.TrimSuffix(answer.Header.Name.String(), "."+serviceName)
+		s.onAnnouncement(usn, txt.TXT)
+	}
+
+	receivedAddrs := 0
+	s.PeerAddrs.Range(func(_, _ interface{}) bool {
+		receivedAddrs++
+		return true
+	})
+	return receivedAddrs == s.expectedPeers
+}
+
+func (s *MdnsService) onAnnouncement(usn string, txt []string) {
+	var channelTag string
+	var multiAddrs []string
+	for _, entry := range txt {
+		if strings.HasPrefix(entry, txtChannelKey) {
+			channelTag = strings.TrimPrefix(entry, txtChannelKey)
+		} else if strings.HasPrefix(entry, txtAddrKey) {
+			multiAddrs = append(multiAddrs, strings.TrimPrefix(entry, txtAddrKey))
+		}
+	}
+	// announcements of other channels (or using a different channel password) have a different channel tag
+	if channelTag != s.channelTag || usn == s.usn {
+		return
+	}
+	if _, ok := s.existingUsns[usn]; ok {
+		return
+	}
+	if _, ok := s.PeerAddrs.Load(usn); ok {
+		return
+	}
+	client.Logger.Debugf("mdns announcement: %s %v", usn, multiAddrs)
+	for _, multiAddr := range multiAddrs {
+		if len(multiAddrs) > 1 && common.IsLoopbackAddr(multiAddr) {
+			continue
+		}
+		if _, err := common.ConvertMultiAddrStrToNormalAddr(multiAddr); err != nil {
+			continue
+		}
+		s.PeerAddrs.Store(usn, multiAddr)
+		client.Logger.Debugf("stored %s (%s)", usn, multiAddr)
+		s.found(multiAddr)
+		break
+	}
+}
+
+func usnOf(key []byte, moniker string) string {
+	return common.ChannelTag(key, "moniker:"+moniker)
+}
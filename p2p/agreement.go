@@ -0,0 +1,145 @@
+package p2p
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/network"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bnb-chain/tss/common"
+)
+
+const (
+	agreementTimeout    = time.Minute
+	agreementLinger     = 3 * time.Second // how long we keep connections after disagreement, so that peers can get our agreement
+	maxAgreementMessage = 64 * 1024
+)
+
+var agreementCodec = common.FrameCodec{MaxSize: maxAgreementMessage}
+
+// Digest identifies the agreement, parties agree with each other when their digests are the same
+func (a *CommitteeAgreement) Digest() []byte {
+	payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(a)
+	if err != nil {
+		common.Panic(fmt.Errorf("cannot marshal committee agreement: %v", err))
+	}
+	hash := sha256.Sum256(payload)
+	return hash[:]
+}
+
+// Diff lists what is different in peer's agreement
+func (a *CommitteeAgreement) Diff(peer *CommitteeAgreement) []string {
+	var diffs []string
+	addDiff := func(name string, ours, theirs interface{}) {
+		if fmt.Sprint(ours) != fmt.Sprint(theirs) {
+			diffs = append(diffs, fmt.Sprintf("%s: ours %v, theirs %v", name, ours, theirs))
+		}
+	}
+	addDiff("mode", a.Mode, peer.Mode)
+	addDiff("threshold", a.Threshold, peer.Threshold)
+	addDiff("parties", a.Parties, peer.Parties)
+	addDiff("new_threshold", a.NewThreshold, peer.NewThreshold)
+	addDiff("new_parties", a.NewParties, peer.NewParties)
+	addDiff("party ids", a.PartyIds, peer.PartyIds)
+	addDiff("new party ids", a.NewPartyIds, peer.NewPartyIds)
+	addDiff("broadcast mode", a.BroadcastMode, peer.BroadcastMode)
+	if !bytes.Equal(a.MessageDigest, peer.MessageDigest) {
+		diffs = append(diffs, fmt.Sprintf("message digest: ours %x, theirs %x", a.MessageDigest, peer.MessageDigest))
+	}
+	return diffs
+}
+
+// AgreementError reports peers that disagree with us (or did not tell us their agreement)
+type AgreementError struct {
+	Diffs map[string][]string // peer id -> what is different or why we cannot get its agreement
+}
+
+func (e *AgreementError) Error() string {
+	peers := make([]string, 0, len(e.Diffs))
+	for peer := range e.Diffs {
+		peers = append(peers, peer)
+	}
+	sort.Strings(peers)
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%d peer(s) do not agree on the committee, please check their configuration:", len(peers))
+	for _, peer := range peers {
+		for _, diff := range e.Diffs[peer] {
+			fmt.Fprintf(&builder, "\n%s: %s", peer, diff)
+		}
+	}
+	return builder.String()
+}
+
+// agree sends our agreement to every connected peer and checks theirs, it should be called before readDataRoutine is started
+func (t *p2pTransporter) agree() error {
+	payload, err := proto.Marshal(t.agreement)
+	if err != nil {
+		return err
+	}
+	payload = append([]byte{AgreementMessagePrefix}, payload...)
+	digest := t.agreement.Digest()
+	logger.Debugf("committee agreement: %x", digest)
+
+	type result struct {
+		pid   string
+		diffs []string
+	}
+	results := make(chan result)
+	numOfPeers := 0
+	t.streams.Range(func(pid, stream interface{}) bool {
+		numOfPeers++
+		if err := t.Send(payload, common.TssClientId(pid.(string))); err != nil {
+			go func() { results <- result{pid.(string), []string{fmt.Sprintf("failed to send agreement: %v", err)}} }()
+			return true
+		}
+		go func() {
+			peerAgreement, err := readAgreement(stream.(network.Stream))
+			if err != nil {
+				results <- result{pid.(string), []string{fmt.Sprintf("failed to receive agreement: %v", err)}}
+			} else if !bytes.Equal(peerAgreement.Digest(), digest) {
+				results <- result{pid.(string), t.agreement.Diff(peerAgreement)}
+			} else {
+				results <- result{pid.(string), nil}
+			}
+		}()
+		return true
+	})
+
+	diffs := make(map[string][]string)
+	for i := 0; i < numOfPeers; i++ {
+		if r := <-results; len(r.diffs) > 0 {
+			diffs[r.pid] = r.diffs
+		}
+	}
+	if len(diffs) > 0 {
+		return &AgreementError{diffs}
+	}
+	logger.Infof("all %d peer(s) agree on the committee", numOfPeers)
+	return nil
+}
+
+// readAgreement reads the first message of a party stream, which should be peer's agreement
+func readAgreement(stream network.Stream) (*CommitteeAgreement, error) {
+	if err := stream.SetReadDeadline(time.Now().Add(agreementTimeout)); err != nil {
+		return nil, err
+	}
+	defer stream.SetReadDeadline(time.Time{})
+
+	var agreement CommitteeAgreement
+	err := agreementCodec.ReadFrameFunc(stream, func(frameType byte, payload []byte) error {
+		if frameType != AgreementMessagePrefix {
+			return fmt.Errorf("peer did not send agreement, it might be running an older version")
+		}
+		return proto.Unmarshal(payload, &agreement)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &agreement, nil
+}
@@ -0,0 +1,222 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.0
+// 	protoc        v3.19.4
+// source: agreement.proto
+
+package p2p
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CommitteeAgreement struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Mode          string   `protobuf:"bytes,1,opt,name=mode,proto3" json:"mode,omitempty"`
+	Threshold     int32    `protobuf:"varint,2,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	Parties       int32    `protobuf:"varint,3,opt,name=parties,proto3" json:"parties,omitempty"`
+	NewThreshold  int32    `protobuf:"varint,4,opt,name=new_threshold,json=newThreshold,proto3" json:"new_threshold,omitempty"`   // regroup only
+	NewParties    int32    `protobuf:"varint,5,opt,name=new_parties,json=newParties,proto3" json:"new_parties,omitempty"`         // regroup only
+	PartyIds      []string `protobuf:"bytes,6,rep,name=party_ids,json=partyIds,proto3" json:"party_ids,omitempty"`                // sorted moniker@id of (old) committee
+	NewPartyIds   []string `protobuf:"bytes,7,rep,name=new_party_ids,json=newPartyIds,proto3" json:"new_party_ids,omitempty"`     // sorted moniker@id of new committee, regroup only
+	MessageDigest []byte   `protobuf:"bytes,8,opt,name=message_digest,json=messageDigest,proto3" json:"message_digest,omitempty"` // sha256 of message to be signed, sign only
+	BroadcastMode string   `protobuf:"bytes,9,opt,name=broadcast_mode,json=broadcastMode,proto3" json:"broadcast_mode,omitempty"` // how broadcasts are checked, see common.P2PConfig.GetBroadcastMode
+}
+
+func (x *CommitteeAgreement) Reset() {
+	*x = CommitteeAgreement{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_agreement_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CommitteeAgreement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommitteeAgreement) ProtoMessage() {}
+
+func (x *CommitteeAgreement) ProtoReflect() protoreflect.Message {
+	mi := &file_agreement_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommitteeAgreement.ProtoReflect.Descriptor instead.
+func (*CommitteeAgreement) Descriptor() ([]byte, []int) {
+	return file_agreement_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CommitteeAgreement) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *CommitteeAgreement) GetThreshold() int32 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+func (x *CommitteeAgreement) GetParties() int32 {
+	if x != nil {
+		return x.Parties
+	}
+	return 0
+}
+
+func (x *CommitteeAgreement) GetNewThreshold() int32 {
+	if x != nil {
+		return x.NewThreshold
+	}
+	return 0
+}
+
+func (x *CommitteeAgreement) GetNewParties() int32 {
+	if x != nil {
+		return x.NewParties
+	}
+	return 0
+}
+
+func (x *CommitteeAgreement) GetPartyIds() []string {
+	if x != nil {
+		return x.PartyIds
+	}
+	return nil
+}
+
+func (x *CommitteeAgreement) GetNewPartyIds() []string {
+	if x != nil {
+		return x.NewPartyIds
+	}
+	return nil
+}
+
+func (x *CommitteeAgreement) GetMessageDigest() []byte {
+	if x != nil {
+		return x.MessageDigest
+	}
+	return nil
+}
+
+func (x *CommitteeAgreement) GetBroadcastMode() string {
+	if x != nil {
+		return x.BroadcastMode
+	}
+	return ""
+}
+
+var File_agreement_proto protoreflect.FileDescriptor
+
+var file_agreement_proto_rawDesc = []byte{
+	0x0a, 0x0f, 0x61, 0x67, 0x72, 0x65, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x12, 0x03, 0x70, 0x32, 0x70, 0x22, 0xb5, 0x02, 0x0a, 0x12, 0x43, 0x6f, 0x6d, 0x6d, 0x69,
+	0x74, 0x74, 0x65, 0x65, 0x41, 0x67, 0x72, 0x65, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x12, 0x0a,
+	0x04, 0x6d, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6d, 0x6f, 0x64,
+	0x65, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x68, 0x72, 0x65, 0x73, 0x68, 0x6f, 0x6c, 0x64, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x74, 0x68, 0x72, 0x65, 0x73, 0x68, 0x6f, 0x6c, 0x64, 0x12,
+	0x18, 0x0a, 0x07, 0x70, 0x61, 0x72, 0x74, 0x69, 0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x07, 0x70, 0x61, 0x72, 0x74, 0x69, 0x65, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x6e, 0x65, 0x77,
+	0x5f, 0x74, 0x68, 0x72, 0x65, 0x73, 0x68, 0x6f, 0x6c, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x0c, 0x6e, 0x65, 0x77, 0x54, 0x68, 0x72, 0x65, 0x73, 0x68, 0x6f, 0x6c, 0x64, 0x12, 0x1f,
+	0x0a, 0x0b, 0x6e, 0x65, 0x77, 0x5f, 0x70, 0x61, 0x72, 0x74, 0x69, 0x65, 0x73, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x0a, 0x6e, 0x65, 0x77, 0x50, 0x61, 0x72, 0x74, 0x69, 0x65, 0x73, 0x12,
+	0x1b, 0x0a, 0x09, 0x70, 0x61, 0x72, 0x74, 0x79, 0x5f, 0x69, 0x64, 0x73, 0x18, 0x06, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x08, 0x70, 0x61, 0x72, 0x74, 0x79, 0x49, 0x64, 0x73, 0x12, 0x22, 0x0a, 0x0d,
+	0x6e, 0x65, 0x77, 0x5f, 0x70, 0x61, 0x72, 0x74, 0x79, 0x5f, 0x69, 0x64, 0x73, 0x18, 0x07, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x0b, 0x6e, 0x65, 0x77, 0x50, 0x61, 0x72, 0x74, 0x79, 0x49, 0x64, 0x73,
+	0x12, 0x25, 0x0a, 0x0e, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x5f, 0x64, 0x69, 0x67, 0x65,
+	0x73, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0d, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67,
+	0x65, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x12, 0x25, 0x0a, 0x0e, 0x62, 0x72, 0x6f, 0x61, 0x64,
+	0x63, 0x61, 0x73, 0x74, 0x5f, 0x6d, 0x6f, 0x64, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0d, 0x62, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x4d, 0x6f, 0x64, 0x65, 0x42, 0x06,
+	0x5a, 0x04, 0x2f, 0x70, 0x32, 0x70, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_agreement_proto_rawDescOnce sync.Once
+	file_agreement_proto_rawDescData = file_agreement_proto_rawDesc
+)
+
+func file_agreement_proto_rawDescGZIP() []byte {
+	file_agreement_proto_rawDescOnce.Do(func() {
+		file_agreement_proto_rawDescData = protoimpl.X.CompressGZIP(file_agreement_proto_rawDescData)
+	})
+	return file_agreement_proto_rawDescData
+}
+
+var file_agreement_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_agreement_proto_goTypes = []interface{}{
+	(*CommitteeAgreement)(nil), // 0: p2p.CommitteeAgreement
+}
+var file_agreement_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_agreement_proto_init() }
+func file_agreement_proto_init() {
+	if File_agreement_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_agreement_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CommitteeAgreement); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_agreement_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_agreement_proto_goTypes,
+		DependencyIndexes: file_agreement_proto_depIdxs,
+		MessageInfos:      file_agreement_proto_msgTypes,
+	}.Build()
+	File_agreement_proto = out.File
+	file_agreement_proto_rawDesc = nil
+	file_agreement_proto_goTypes = nil
+	file_agreement_proto_depIdxs = nil
+}
@@ -0,0 +1,248 @@
+package p2p
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bnb-chain/tss-lib/v2/tss"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bnb-chain/tss/common"
+)
+
+// broadcastKey identifies a broadcast regardless of its content, as a party broadcasts one message of each type in a protocol run.
+// So that conflicting versions of the same broadcast are checked against each other
+func broadcastKey(sender string, originMsg []byte) (p2pMessageKey, error) {
+	var m tss.MessageWrapper
+	if err := proto.Unmarshal(originMsg, &m); err != nil {
+		return "", fmt.Errorf("not a valid tss message: %v", err)
+	}
+	if m.From.GetId() != sender {
+		return "", fmt.Errorf("message is from %s rather than %s", m.From.GetId(), sender)
+	}
+	return p2pMessageKey(fmt.Sprintf("%s/%s", sender, m.Message.GetTypeUrl())), nil
+}
+
+// senderOf returns the sender part of broadcast key
+func senderOf(key p2pMessageKey) string {
+	return strings.SplitN(string(key), "/", 2)[0]
+}
+
+// roundOf returns the message type part of broadcast key, broadcasts of the same type are sent in the same round
+func roundOf(key p2pMessageKey) string {
+	if parts := strings.SplitN(string(key), "/", 2); len(parts) == 2 {
+		return parts[1]
+	}
+	return ""
+}
+
+// broadcastSigningBytes is what sender signs for a broadcast, the key binds the hash to the broadcast,
+// so that a hash relayed without the message cannot be claimed for another broadcast of the sender
+func broadcastSigningBytes(key p2pMessageKey, hash []byte) []byte {
+	return append([]byte(key+"\x00"), hash...)
+}
+
+// signingBytes is what relayer signs, the message itself with relayer signature unset
+func (m *P2PMessageWithHash) signingBytes() []byte {
+	unsigned := proto.Clone(m).(*P2PMessageWithHash)
+	unsigned.RelayerSignature = nil
+	payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(unsigned)
+	if err != nil {
+		common.Panic(fmt.Errorf("cannot marshal P2PMessageWithHash: %v", err))
+	}
+	return payload
+}
+
+// verifySender checks the relayed hash (and message if there is) is of a broadcast signed by its sender
+func (m *P2PMessageWithHash) verifySender() error {
+	if senderOf(p2pMessageKey(m.Key)) != m.From {
+		return fmt.Errorf("broadcast %s is not from %s", m.Key, m.From)
+	}
+	if len(m.OriginMsg) > 0 {
+		hash := sha256.Sum256(m.OriginMsg)
+		if !bytes.Equal(hash[:], m.Hash) {
+			return fmt.Errorf("hash doesn't match the message")
+		}
+		if key, err := broadcastKey(m.From, m.OriginMsg); err != nil {
+			return err
+		} else if string(key) != m.Key {
+			return fmt.Errorf("message is of broadcast %s rather than %s", key, m.Key)
+		}
+	}
+	return verifySignature(m.From, broadcastSigningBytes(p2pMessageKey(m.Key), m.Hash), m.SenderSignature)
+}
+
+// signingBytes is what reporter signs, the blame itself with signature unset
+func (b *Blame) signingBytes() []byte {
+	unsigned := proto.Clone(b).(*Blame)
+	unsigned.Signature = nil
+	payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(unsigned)
+	if err != nil {
+		common.Panic(fmt.Errorf("cannot marshal Blame: %v", err))
+	}
+	return payload
+}
+
+func (b *Blame) verdict(monikerOf func(pid string) string) string {
+	culprits := make([]string, 0, len(b.Culprits))
+	for _, culprit := range b.Culprits {
+		culprits = append(culprits, fmt.Sprintf("%s(%s)", monikerOf(culprit), culprit))
+	}
+	return fmt.Sprintf("%s blames [%s]: %s", monikerOf(b.Reporter), strings.Join(culprits, ", "), b.Reason)
+}
+
+// judge decides who is malicious from what parties say they received of the same broadcast(s).
+// Every statement is signed by its relayer and carries the sender's signature, so anyone can reach the same verdict from the evidence:
+// a relayer is malicious if the message it relays is not signed by the sender, otherwise the sender is malicious if it signed different messages of one broadcast
+func judge(evidence []*P2PMessageWithHash) (culprits []string, reason string) {
+	var reasons []string
+	blamed := make(map[string]bool)
+	hashes := make(map[p2pMessageKey]map[string]bool)
+	for _, relay := range evidence {
+		if err := verifySignature(relay.Relayer, relay.signingBytes(), relay.RelayerSignature); err != nil {
+			reasons = append(reasons, fmt.Sprintf("ignored a statement not signed by %s: %v", relay.Relayer, err))
+			continue
+		}
+		if err := relay.verifySender(); err != nil {
+			blamed[relay.Relayer] = true
+			reasons = append(reasons, fmt.Sprintf("%s relayed a message which was not sent by %s: %v", relay.Relayer, relay.From, err))
+			continue
+		}
+		key := p2pMessageKey(relay.Key)
+		if hashes[key] == nil {
+			hashes[key] = make(map[string]bool)
+		}
+		hashes[key][string(relay.Hash)] = true
+	}
+	for key, versions := range hashes {
+		if len(versions) > 1 {
+			sender := senderOf(key)
+			blamed[sender] = true
+			reasons = append(reasons, fmt.Sprintf("%s sent %d different messages of broadcast %s", sender, len(versions), key))
+		}
+	}
+
+	for culprit := range blamed {
+		culprits = append(culprits, culprit)
+	}
+	sort.Strings(culprits)
+	sort.Strings(reasons)
+	return culprits, strings.Join(reasons, "; ")
+}
+
+// reportBlame blames in background only once, later inconsistencies are ignored as the session is failing anyway.
+// It doesn't block because read routines must go on receiving acknowledgements of the blame
+func (t *p2pTransporter) reportBlame(evidence []*P2PMessageWithHash) {
+	t.blameOnce.Do(func() {
+		go t.blame(evidence)
+	})
+}
+
+// blame tells the committee who is malicious and why, writes the evidence to vault and fails the session with the verdict
+func (t *p2pTransporter) blame(evidence []*P2PMessageWithHash) {
+	culprits, reason := judge(evidence)
+	b := &Blame{
+		Reporter: t.host.ID().Pretty(),
+		Culprits: culprits,
+		Reason:   reason,
+		Evidence: evidence,
+	}
+	b.Signature = t.sign(b.signingBytes())
+	logger.Error(b.verdict(t.monikerOf))
+
+	payload, err := proto.Marshal(b)
+	if err != nil {
+		common.Panic(fmt.Errorf("cannot marshal Blame: %v", err))
+	}
+	payload = append([]byte{BlameMessagePrefix}, payload...)
+	t.sessions.Range(func(pid, _ interface{}) bool {
+		if err := t.Send(payload, common.TssClientId(pid.(string))); err != nil {
+			logger.Errorf("failed to send blame to %s: %v", pid, err)
+		}
+		return true
+	})
+	if err := t.WaitForDelivery(drainTimeout); err != nil {
+		logger.Errorf("blame might not reach all peers: %v", err)
+	}
+	// the verdict is reported as an error of the first culprit, or of ourselves if no statement could be checked
+	blamed := b.Reporter
+	if len(culprits) > 0 {
+		blamed = culprits[0]
+	}
+	t.reportError(blamed, fmt.Errorf("someone in network is malicious (%s), evidence is written to %s", b.verdict(t.monikerOf), t.writeEvidence(b)))
+}
+
+// handleBlame checks the evidence of blame from peer on our own and fails the session, as the protocol cannot continue without the reporter.
+// A blame the evidence doesn't support is misbehavior of its reporter
+func (t *p2pTransporter) handleBlame(pid string, b *Blame) {
+	if b.Reporter != pid {
+		logger.Errorf("dropped a forged blame from %s, claimed reporter: %s", pid, b.Reporter)
+		return
+	}
+	if err := verifySignature(b.Reporter, b.signingBytes(), b.Signature); err != nil {
+		logger.Errorf("dropped a blame not signed by %s: %v", pid, err)
+		return
+	}
+	logger.Error(b.verdict(t.monikerOf))
+	evidenceFile := t.writeEvidence(b)
+	if culprits, reason := judge(b.Evidence); strings.Join(culprits, ",") != strings.Join(b.Culprits, ",") {
+		t.reportMisbehavior(pid, fmt.Errorf("evidence written to %s doesn't support the blame, it shows %v: %s", evidenceFile, culprits, reason))
+		return
+	}
+	t.reportError(pid, fmt.Errorf("confirmed the blame from %s, evidence is written to %s", t.monikerOf(pid), evidenceFile))
+}
+
+// writeEvidence saves the blame in vault, returns the path of evidence file
+func (t *p2pTransporter) writeEvidence(b *Blame) string {
+	payload, err := protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(b)
+	if err != nil {
+		common.Panic(fmt.Errorf("cannot marshal Blame: %v", err))
+	}
+	pathToEvidence := path.Join(t.pathToVault, fmt.Sprintf("evidence-%s-%s.json", time.Now().Format("20060102150405"), t.monikerOf(b.Reporter)))
+	if err := ioutil.WriteFile(pathToEvidence, payload, 0600); err != nil {
+		logger.Errorf("failed to write evidence: %v\n%s", err, payload)
+	}
+	return pathToEvidence
+}
+
+func (t *p2pTransporter) sign(data []byte) []byte {
+	signature, err := t.host.Peerstore().PrivKey(t.host.ID()).Sign(data)
+	if err != nil {
+		common.Panic(fmt.Errorf("cannot sign with node key: %v", err))
+	}
+	return signature
+}
+
+// verifySignature verifies signature against the node key that party id is derived from
+func verifySignature(pid string, data, signature []byte) error {
+	id, err := peer.IDB58Decode(pid)
+	if err != nil {
+		return err
+	}
+	pubKey, err := id.ExtractPublicKey()
+	if err != nil {
+		return err
+	}
+	if ok, err := pubKey.Verify(data, signature); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// monikerOf returns moniker of party pid, or pid itself if it is not a party of this session
+func (t *p2pTransporter) monikerOf(pid string) string {
+	if moniker, ok := t.monikers[pid]; ok {
+		return moniker
+	}
+	return pid
+}
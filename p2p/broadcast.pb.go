@@ -0,0 +1,172 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.0
+// 	protoc        v3.19.4
+// source: broadcast.proto
+
+package p2p
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ReliableBroadcastMessage struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Key             string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`                                                // which broadcast, <sender>/<message type>
+	Hash            []byte `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`                                              // sha256 of origin_msg
+	OriginMsg       []byte `protobuf:"bytes,3,opt,name=origin_msg,json=originMsg,proto3" json:"origin_msg,omitempty"`                   // marshaled tss.MessageWrapper, echo only
+	SenderSignature []byte `protobuf:"bytes,4,opt,name=sender_signature,json=senderSignature,proto3" json:"sender_signature,omitempty"` // sender's node key signature of key and hash, echo only
+}
+
+func (x *ReliableBroadcastMessage) Reset() {
+	*x = ReliableBroadcastMessage{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_broadcast_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReliableBroadcastMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReliableBroadcastMessage) ProtoMessage() {}
+
+func (x *ReliableBroadcastMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_broadcast_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReliableBroadcastMessage.ProtoReflect.Descriptor instead.
+func (*ReliableBroadcastMessage) Descriptor() ([]byte, []int) {
+	return file_broadcast_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ReliableBroadcastMessage) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ReliableBroadcastMessage) GetHash() []byte {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+func (x *ReliableBroadcastMessage) GetOriginMsg() []byte {
+	if x != nil {
+		return x.OriginMsg
+	}
+	return nil
+}
+
+func (x *ReliableBroadcastMessage) GetSenderSignature() []byte {
+	if x != nil {
+		return x.SenderSignature
+	}
+	return nil
+}
+
+var File_broadcast_proto protoreflect.FileDescriptor
+
+var file_broadcast_proto_rawDesc = []byte{
+	0x0a, 0x0f, 0x62, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x12, 0x03, 0x70, 0x32, 0x70, 0x22, 0x8a, 0x01, 0x0a, 0x18, 0x52, 0x65, 0x6c, 0x69, 0x61,
+	0x62, 0x6c, 0x65, 0x42, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x4d, 0x65, 0x73, 0x73,
+	0x61, 0x67, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x12, 0x1d, 0x0a, 0x0a, 0x6f, 0x72, 0x69,
+	0x67, 0x69, 0x6e, 0x5f, 0x6d, 0x73, 0x67, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x6f,
+	0x72, 0x69, 0x67, 0x69, 0x6e, 0x4d, 0x73, 0x67, 0x12, 0x29, 0x0a, 0x10, 0x73, 0x65, 0x6e, 0x64,
+	0x65, 0x72, 0x5f, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x0f, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74,
+	0x75, 0x72, 0x65, 0x42, 0x06, 0x5a, 0x04, 0x2f, 0x70, 0x32, 0x70, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
+}
+
+var (
+	file_broadcast_proto_rawDescOnce sync.Once
+	file_broadcast_proto_rawDescData = file_broadcast_proto_rawDesc
+)
+
+func file_broadcast_proto_rawDescGZIP() []byte {
+	file_broadcast_proto_rawDescOnce.Do(func() {
+		file_broadcast_proto_rawDescData = protoimpl.X.CompressGZIP(file_broadcast_proto_rawDescData)
+	})
+	return file_broadcast_proto_rawDescData
+}
+
+var file_broadcast_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_broadcast_proto_goTypes = []interface{}{
+	(*ReliableBroadcastMessage)(nil), // 0: p2p.ReliableBroadcastMessage
+}
+var file_broadcast_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_broadcast_proto_init() }
+func file_broadcast_proto_init() {
+	if File_broadcast_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_broadcast_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReliableBroadcastMessage); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_broadcast_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_broadcast_proto_goTypes,
+		DependencyIndexes: file_broadcast_proto_depIdxs,
+		MessageInfos:      file_broadcast_proto_msgTypes,
+	}.Build()
+	File_broadcast_proto = out.File
+	file_broadcast_proto_rawDesc = nil
+	file_broadcast_proto_goTypes = nil
+	file_broadcast_proto_depIdxs = nil
+}
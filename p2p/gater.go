@@ -0,0 +1,39 @@
+package p2p
+
+import (
+	"github.com/ipfs/go-log"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// security events (i.e. refused peers) are logged under a logger of their own, so that operators can collect them apart from transport logs
+var securityLogger = log.Logger("security")
+
+// gater refuses inbound connections from peers that are neither expected by a session running on the host nor bootstrap/relay peers.
+// libp2p (v0.3.0) has no connection gater yet, a connection is closed in Connected notification before the swarm accepts any stream over it.
+// Outbound connections are not gated, as dht might dial peers other than the committee to find them
+type gater struct {
+	h *Host
+}
+
+var _ network.Notifiee = (*gater)(nil)
+
+func (g *gater) Connected(n network.Network, c network.Conn) {
+	if c.Stat().Direction != network.DirInbound || g.h.allows(c.RemotePeer()) {
+		return
+	}
+	securityLogger.Warningf("refused connection from unexpected peer %s (%s)", c.RemotePeer().Pretty(), c.RemoteMultiaddr())
+	if err := c.Close(); err != nil {
+		logger.Debugf("failed to close connection from %s: %v", c.RemotePeer().Pretty(), err)
+	}
+}
+
+func (g *gater) Disconnected(n network.Network, c network.Conn) {}
+
+func (g *gater) Listen(n network.Network, addr multiaddr.Multiaddr) {}
+
+func (g *gater) ListenClose(n network.Network, addr multiaddr.Multiaddr) {}
+
+func (g *gater) OpenedStream(n network.Network, s network.Stream) {}
+
+func (g *gater) ClosedStream(n network.Network, s network.Stream) {}
@@ -28,8 +28,12 @@ type P2PMessageWithHash struct {
 	From                    string   `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
 	To                      []string `protobuf:"bytes,2,rep,name=to,proto3" json:"to,omitempty"`
 	Hash                    []byte   `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`
-	OriginMsg               []byte   `protobuf:"bytes,4,opt,name=originMsg,proto3" json:"originMsg,omitempty"`
+	OriginMsg               []byte   `protobuf:"bytes,4,opt,name=originMsg,proto3" json:"originMsg,omitempty"`                                                                     // empty in BroadcastDigest
 	IsToOldAndNewCommittees bool     `protobuf:"varint,5,opt,name=is_to_old_and_new_committees,json=isToOldAndNewCommittees,proto3" json:"is_to_old_and_new_committees,omitempty"` // used only in certain resharing messages
+	SenderSignature         []byte   `protobuf:"bytes,6,opt,name=sender_signature,json=senderSignature,proto3" json:"sender_signature,omitempty"`                                  // sender's node key signature of key and hash, see broadcastSigningBytes
+	Relayer                 string   `protobuf:"bytes,7,opt,name=relayer,proto3" json:"relayer,omitempty"`                                                                         // party id of who relays this hash
+	RelayerSignature        []byte   `protobuf:"bytes,8,opt,name=relayer_signature,json=relayerSignature,proto3" json:"relayer_signature,omitempty"`                               // relayer's node key signature of this message with relayer_signature unset
+	Key                     string   `protobuf:"bytes,9,opt,name=key,proto3" json:"key,omitempty"`                                                                                 // which broadcast, <sender>/<message type>
 }
 
 func (x *P2PMessageWithHash) Reset() {
@@ -99,11 +103,228 @@ func (x *P2PMessageWithHash) GetIsToOldAndNewCommittees() bool {
 	return false
 }
 
+func (x *P2PMessageWithHash) GetSenderSignature() []byte {
+	if x != nil {
+		return x.SenderSignature
+	}
+	return nil
+}
+
+func (x *P2PMessageWithHash) GetRelayer() string {
+	if x != nil {
+		return x.Relayer
+	}
+	return ""
+}
+
+func (x *P2PMessageWithHash) GetRelayerSignature() []byte {
+	if x != nil {
+		return x.RelayerSignature
+	}
+	return nil
+}
+
+func (x *P2PMessageWithHash) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type BroadcastDigest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Round      string                `protobuf:"bytes,1,opt,name=round,proto3" json:"round,omitempty"`           // message type of broadcasts in this round
+	Broadcasts []*P2PMessageWithHash `protobuf:"bytes,2,rep,name=broadcasts,proto3" json:"broadcasts,omitempty"` // what we received in this round, without origin_msg
+}
+
+func (x *BroadcastDigest) Reset() {
+	*x = BroadcastDigest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_hash_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BroadcastDigest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BroadcastDigest) ProtoMessage() {}
+
+func (x *BroadcastDigest) ProtoReflect() protoreflect.Message {
+	mi := &file_hash_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BroadcastDigest.ProtoReflect.Descriptor instead.
+func (*BroadcastDigest) Descriptor() ([]byte, []int) {
+	return file_hash_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BroadcastDigest) GetRound() string {
+	if x != nil {
+		return x.Round
+	}
+	return ""
+}
+
+func (x *BroadcastDigest) GetBroadcasts() []*P2PMessageWithHash {
+	if x != nil {
+		return x.Broadcasts
+	}
+	return nil
+}
+
+type SignedBroadcast struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Msg       []byte `protobuf:"bytes,1,opt,name=msg,proto3" json:"msg,omitempty"`             // marshaled tss.MessageWrapper
+	Signature []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"` // sender's node key signature of key and sha256 of msg, see broadcastSigningBytes
+}
+
+func (x *SignedBroadcast) Reset() {
+	*x = SignedBroadcast{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_hash_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SignedBroadcast) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignedBroadcast) ProtoMessage() {}
+
+func (x *SignedBroadcast) ProtoReflect() protoreflect.Message {
+	mi := &file_hash_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignedBroadcast.ProtoReflect.Descriptor instead.
+func (*SignedBroadcast) Descriptor() ([]byte, []int) {
+	return file_hash_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SignedBroadcast) GetMsg() []byte {
+	if x != nil {
+		return x.Msg
+	}
+	return nil
+}
+
+func (x *SignedBroadcast) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+type Blame struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Reporter  string                `protobuf:"bytes,1,opt,name=reporter,proto3" json:"reporter,omitempty"` // party id of who finds the inconsistent broadcast
+	Culprits  []string              `protobuf:"bytes,2,rep,name=culprits,proto3" json:"culprits,omitempty"` // party ids
+	Reason    string                `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	Evidence  []*P2PMessageWithHash `protobuf:"bytes,4,rep,name=evidence,proto3" json:"evidence,omitempty"`   // what each party says it received from the sender, signed by that party
+	Signature []byte                `protobuf:"bytes,5,opt,name=signature,proto3" json:"signature,omitempty"` // reporter's node key signature of this message with signature unset
+}
+
+func (x *Blame) Reset() {
+	*x = Blame{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_hash_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Blame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Blame) ProtoMessage() {}
+
+func (x *Blame) ProtoReflect() protoreflect.Message {
+	mi := &file_hash_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Blame.ProtoReflect.Descriptor instead.
+func (*Blame) Descriptor() ([]byte, []int) {
+	return file_hash_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Blame) GetReporter() string {
+	if x != nil {
+		return x.Reporter
+	}
+	return ""
+}
+
+func (x *Blame) GetCulprits() []string {
+	if x != nil {
+		return x.Culprits
+	}
+	return nil
+}
+
+func (x *Blame) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Blame) GetEvidence() []*P2PMessageWithHash {
+	if x != nil {
+		return x.Evidence
+	}
+	return nil
+}
+
+func (x *Blame) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
 var File_hash_proto protoreflect.FileDescriptor
 
 var file_hash_proto_rawDesc = []byte{
 	0x0a, 0x0a, 0x68, 0x61, 0x73, 0x68, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x03, 0x70, 0x32,
-	0x70, 0x22, 0xa9, 0x01, 0x0a, 0x12, 0x50, 0x32, 0x70, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65,
+	0x70, 0x22, 0xad, 0x02, 0x0a, 0x12, 0x50, 0x32, 0x70, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65,
 	0x57, 0x69, 0x74, 0x68, 0x48, 0x61, 0x73, 0x68, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d,
 	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02,
 	0x74, 0x6f, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x02, 0x74, 0x6f, 0x12, 0x12, 0x0a, 0x04,
@@ -113,8 +334,38 @@ var file_hash_proto_rawDesc = []byte{
 	0x0a, 0x1c, 0x69, 0x73, 0x5f, 0x74, 0x6f, 0x5f, 0x6f, 0x6c, 0x64, 0x5f, 0x61, 0x6e, 0x64, 0x5f,
 	0x6e, 0x65, 0x77, 0x5f, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x74, 0x65, 0x65, 0x73, 0x18, 0x05,
 	0x20, 0x01, 0x28, 0x08, 0x52, 0x17, 0x69, 0x73, 0x54, 0x6f, 0x4f, 0x6c, 0x64, 0x41, 0x6e, 0x64,
-	0x4e, 0x65, 0x77, 0x43, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x74, 0x65, 0x65, 0x73, 0x42, 0x06, 0x5a,
-	0x04, 0x2f, 0x70, 0x32, 0x70, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x4e, 0x65, 0x77, 0x43, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x74, 0x65, 0x65, 0x73, 0x12, 0x29, 0x0a,
+	0x10, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x5f, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72,
+	0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0f, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x53,
+	0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x72, 0x65, 0x6c, 0x61,
+	0x79, 0x65, 0x72, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x72, 0x65, 0x6c, 0x61, 0x79,
+	0x65, 0x72, 0x12, 0x2b, 0x0a, 0x11, 0x72, 0x65, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x5f, 0x73, 0x69,
+	0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x10, 0x72,
+	0x65, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12,
+	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
+	0x79, 0x22, 0x60, 0x0a, 0x0f, 0x42, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x44, 0x69,
+	0x67, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x6f, 0x75, 0x6e, 0x64, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x05, 0x72, 0x6f, 0x75, 0x6e, 0x64, 0x12, 0x37, 0x0a, 0x0a, 0x62, 0x72,
+	0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x17,
+	0x2e, 0x70, 0x32, 0x70, 0x2e, 0x50, 0x32, 0x70, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x57,
+	0x69, 0x74, 0x68, 0x48, 0x61, 0x73, 0x68, 0x52, 0x0a, 0x62, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61,
+	0x73, 0x74, 0x73, 0x22, 0x41, 0x0a, 0x0f, 0x53, 0x69, 0x67, 0x6e, 0x65, 0x64, 0x42, 0x72, 0x6f,
+	0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x73, 0x67, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x03, 0x6d, 0x73, 0x67, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e,
+	0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67,
+	0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x22, 0xaa, 0x01, 0x0a, 0x05, 0x42, 0x6c, 0x61, 0x6d, 0x65,
+	0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x72, 0x12, 0x1a, 0x0a, 0x08,
+	0x63, 0x75, 0x6c, 0x70, 0x72, 0x69, 0x74, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08,
+	0x63, 0x75, 0x6c, 0x70, 0x72, 0x69, 0x74, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73,
+	0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e,
+	0x12, 0x33, 0x0a, 0x08, 0x65, 0x76, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x04, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x17, 0x2e, 0x70, 0x32, 0x70, 0x2e, 0x50, 0x32, 0x70, 0x4d, 0x65, 0x73, 0x73,
+	0x61, 0x67, 0x65, 0x57, 0x69, 0x74, 0x68, 0x48, 0x61, 0x73, 0x68, 0x52, 0x08, 0x65, 0x76, 0x69,
+	0x64, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75,
+	0x72, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74,
+	0x75, 0x72, 0x65, 0x42, 0x06, 0x5a, 0x04, 0x2f, 0x70, 0x32, 0x70, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
 }
 
 var (
@@ -129,16 +380,21 @@ func file_hash_proto_rawDescGZIP() []byte {
 	return file_hash_proto_rawDescData
 }
 
-var file_hash_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_hash_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
 var file_hash_proto_goTypes = []interface{}{
 	(*P2PMessageWithHash)(nil), // 0: p2p.P2pMessageWithHash
+	(*BroadcastDigest)(nil),    // 1: p2p.BroadcastDigest
+	(*SignedBroadcast)(nil),    // 2: p2p.SignedBroadcast
+	(*Blame)(nil),              // 3: p2p.Blame
 }
 var file_hash_proto_depIdxs = []int32{
-	0, // [0:0] is the sub-list for method output_type
-	0, // [0:0] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0, // 0: p2p.BroadcastDigest.broadcasts:type_name -> p2p.P2pMessageWithHash
+	0, // 1: p2p.Blame.evidence:type_name -> p2p.P2pMessageWithHash
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_hash_proto_init() }
@@ -159,6 +415,42 @@ func file_hash_proto_init() {
 				return nil
 			}
 		}
+		file_hash_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BroadcastDigest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_hash_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SignedBroadcast); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_hash_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Blame); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -166,7 +458,7 @@ func file_hash_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_hash_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   1,
+			NumMessages:   4,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -0,0 +1,190 @@
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p"
+	relay "github.com/libp2p/go-libp2p-circuit"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	libp2pdht "github.com/libp2p/go-libp2p-kad-dht"
+	opts "github.com/libp2p/go-libp2p-kad-dht/opts"
+	"github.com/libp2p/go-libp2p-peerstore/pstoremem"
+	"github.com/multiformats/go-multiaddr"
+
+	"github.com/bnb-chain/tss/common"
+)
+
+// Host is a libp2p host with the node key of a vault, it can be shared by transporters of concurrent sessions,
+// i.e. a daemon stays connected to the committee and runs many keygen and sign sessions over it.
+// Each session talks on streams of its own protocol ids (see sessionProtocol), so that messages of different sessions never mix
+type Host struct {
+	host.Host
+
+	ctx         context.Context
+	dht         *libp2pdht.IpfsDHT
+	nodeKey     []byte
+	pathToVault string
+	relayPeers  []multiaddr.Multiaddr
+	trusted     map[peer.ID]bool // bootstrap and relay peers, which are allowed to connect us besides expected peers of sessions
+
+	sessions sync.Map // session id -> *p2pTransporter
+}
+
+// NewHost creates a host listening on config.ListenAddr with the node key in vault, and connects it to bootstrap and relay peers in config
+func NewHost(home, vault string, config *common.P2PConfig) *Host {
+	h := &Host{
+		ctx:         context.Background(),
+		pathToVault: path.Join(home, vault),
+		trusted:     make(map[peer.ID]bool),
+	}
+	for _, bootstrapAddr := range config.BootstrapPeers {
+		bootstrapPeerInfo, err := peer.AddrInfoFromP2pAddr(bootstrapAddr)
+		if err != nil {
+			common.Panic(err)
+		}
+		h.trusted[bootstrapPeerInfo.ID] = true
+	}
+	// TODO: relay addr need further confirm
+	// The correct address should be /p2p-circuit/p2p/<dest ID> rather than /p2p-circuit/p2p/<relay ID>
+	for _, relayPeerAddr := range config.RelayPeers {
+		relayPeerInfo, err := peer.AddrInfoFromP2pAddr(relayPeerAddr)
+		if err != nil {
+			common.Panic(err)
+		}
+		h.trusted[relayPeerInfo.ID] = true
+		relayAddr, err := multiaddr.NewMultiaddr("/p2p-circuit/p2p/" + relayPeerInfo.ID.Pretty())
+		if err != nil {
+			common.Panic(err)
+		}
+		h.relayPeers = append(h.relayPeers, relayAddr)
+	}
+
+	// load private key of node id
+	var privKey crypto.PrivKey
+	pathToNodeKey := path.Join(h.pathToVault, "node_key")
+	if _, err := os.Stat(pathToNodeKey); err == nil {
+		bytes, err := ioutil.ReadFile(pathToNodeKey)
+		if err != nil {
+			common.Panic(err)
+		}
+		privKey, err = crypto.UnmarshalPrivateKey(bytes)
+		if err != nil {
+			common.Panic(err)
+		}
+		h.nodeKey = bytes
+	}
+
+	addr, err := multiaddr.NewMultiaddr(config.ListenAddr)
+	if err != nil {
+		common.Panic(err)
+	}
+
+	h.Host, err = libp2p.New(
+		h.ctx,
+		libp2p.Peerstore(pstoremem.NewPeerstore()),
+		libp2p.ListenAddrs(addr),
+		libp2p.Identity(privKey),
+		libp2p.EnableRelay(relay.OptDiscovery),
+		libp2p.NATPortMap(), // actually I cannot find a case that NATPortMap can help, but in case some edge case, created it to save relay server performance
+	)
+	if err != nil {
+		common.Panic(err)
+	}
+	// registered before connecting anyone, so that no inbound connection slips through
+	h.Network().Notify(&gater{h})
+	logger.Debug("Host created. We are:", h.ID())
+	logger.Debug("listening on:", h.Addrs())
+
+	h.dht = h.setupDHTClient(config.BootstrapPeers)
+	return h
+}
+
+// sessionProtocol scopes protocol to a session, sessions of the command line (whose host is not shared) use protocol as is
+func sessionProtocol(protocolId, sessionId string) protocol.ID {
+	if sessionId == "" {
+		return protocol.ID(protocolId)
+	}
+	return protocol.ID(fmt.Sprintf("%s/%s", protocolId, sessionId))
+}
+
+// register reserves session id for t, a session id can only be used by one running session on the host
+func (h *Host) register(sessionId string, t *p2pTransporter) error {
+	if _, loaded := h.sessions.LoadOrStore(sessionId, t); loaded {
+		return fmt.Errorf("session %s is already running on this host", sessionId)
+	}
+	return nil
+}
+
+func (h *Host) unregister(sessionId string) {
+	h.sessions.Delete(sessionId)
+}
+
+// allows tells whether pid is allowed to connect us, i.e. it is expected by a running session.
+// A peer connecting before its session is registered is refused, it will be connected once it redials
+func (h *Host) allows(pid peer.ID) bool {
+	if h.trusted[pid] {
+		return true
+	}
+	allowed := false
+	h.sessions.Range(func(_, t interface{}) bool {
+		allowed = t.(*p2pTransporter).isExpected(pid)
+		return !allowed
+	})
+	return allowed
+}
+
+func (h *Host) setupDHTClient(bootstrapPeers []multiaddr.Multiaddr) *libp2pdht.IpfsDHT {
+	//ds, err := leveldb.NewDatastore(t.pathToRouteTable, nil)
+	//if err != nil {
+	//	common.Panic(err)
+	//}
+	ds := datastore.NewMapDatastore()
+
+	kademliaDHT, err := libp2pdht.New(
+		h.ctx,
+		h.Host,
+		opts.Datastore(ds),
+		opts.Client(true),
+	)
+	if err != nil {
+		common.Panic(err)
+	}
+
+	// Connect to bootstrap peers
+	for _, bootstrapAddr := range bootstrapPeers {
+		bootstrapPeerInfo, err := peer.AddrInfoFromP2pAddr(bootstrapAddr)
+		if err != nil {
+			common.Panic(err)
+		}
+		if err := h.Connect(h.ctx, *bootstrapPeerInfo); err != nil {
+			logger.Warning(err)
+		} else {
+			logger.Info("Connection established with bootstrap node:", *bootstrapPeerInfo)
+		}
+	}
+
+	// Connect to relay peers to get NAT support
+	// TODO: exclude relay peers that are same with bootstrap peers
+	for _, relayAddr := range h.relayPeers {
+		relayPeerInfo, err := peer.AddrInfoFromP2pAddr(relayAddr)
+		if err != nil {
+			common.Panic(err)
+		}
+		if err := h.Connect(h.ctx, *relayPeerInfo); err != nil {
+			logger.Warning(err)
+		} else {
+			logger.Info("Connection established with relay node:", *relayPeerInfo)
+		}
+	}
+
+	return kademliaDHT
+}
@@ -0,0 +1,210 @@
+package p2p
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bnb-chain/tss-lib/v2/tss"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/bnb-chain/tss/common"
+)
+
+type testParty struct {
+	moniker string
+	id      string
+	host    *Host
+}
+
+// newTestParty creates a host listening on a random loopback port with a node key in a vault under home
+func newTestParty(t *testing.T, home, moniker string) *testParty {
+	privKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bytes, err := crypto.MarshalPrivateKey(privKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(path.Join(home, moniker), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(home, moniker, "node_key"), bytes, 0600); err != nil {
+		t.Fatal(err)
+	}
+	id, err := peer.IDFromPrivateKey(privKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHost(home, moniker, &common.P2PConfig{ListenAddr: "/ip4/127.0.0.1/tcp/0"})
+	t.Cleanup(func() { h.Close() })
+	return &testParty{moniker: moniker, id: id.Pretty(), host: h}
+}
+
+func (p *testParty) config(home string, peer *testParty) *common.TssConfig {
+	return &common.TssConfig{
+		P2PConfig: common.P2PConfig{
+			ExpectedPeers: []string{fmt.Sprintf("%s@%s", peer.moniker, peer.id)},
+			PeerAddrs:     []string{peer.host.Addrs()[0].String()},
+		},
+		Id:      common.TssClientId(p.id),
+		Moniker: p.moniker,
+		Home:    home,
+		Vault:   p.moniker,
+	}
+}
+
+// message is a tss message from sender, its type url tells which session it is sent in
+func message(t *testing.T, sender *testParty, sessionId string) []byte {
+	payload, err := proto.Marshal(&tss.MessageWrapper{
+		From:    &tss.MessageWrapper_PartyID{Id: sender.id, Moniker: sender.moniker},
+		Message: &anypb.Any{TypeUrl: sessionId},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append([]byte{MessagePrefix}, payload...)
+}
+
+func receive(t *testing.T, transporter common.Transporter) string {
+	select {
+	case msg := <-transporter.ReceiveCh():
+		var m tss.MessageWrapper
+		if err := proto.Unmarshal(msg.MessageWrapperBytes, &m); err != nil {
+			t.Fatal(err)
+		}
+		return m.Message.GetTypeUrl()
+	case <-time.After(time.Minute):
+		t.Fatal("message is not received within a minute")
+		return ""
+	}
+}
+
+func TestSessionsShareHost(t *testing.T) {
+	home := t.TempDir()
+	a, b := newTestParty(t, home, "a"), newTestParty(t, home, "b")
+	sessionIds := []string{"session1", "session2"}
+
+	// sessions block until peers are connected, so both sides of both sessions are created concurrently
+	transporters := make(map[string]common.Transporter) // <moniker>/<session id> -> transporter
+	mtx := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, sessionId := range sessionIds {
+		for _, pair := range [][2]*testParty{{a, b}, {b, a}} {
+			wg.Add(1)
+			go func(self, peer *testParty, sessionId string) {
+				defer wg.Done()
+				transporter, err := NewSessionTransporter(self.host, sessionId, nil, nil, nil, nil, nil, self.config(home, peer))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mtx.Lock()
+				transporters[self.moniker+"/"+sessionId] = transporter
+				mtx.Unlock()
+			}(pair[0], pair[1], sessionId)
+		}
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+
+	for _, sessionId := range sessionIds {
+		if err := transporters["a/"+sessionId].Send(message(t, a, sessionId), common.TssClientId(b.id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// messages of a session are only received by the same session of peer
+	for _, sessionId := range sessionIds {
+		if received := receive(t, transporters["b/"+sessionId]); received != sessionId {
+			t.Fatalf("%s received a message of %s", sessionId, received)
+		}
+	}
+
+	// a session ending doesn't affect others on the host, and peer learns it said bye
+	for _, self := range []string{"a", "b"} {
+		if err := transporters[self+"/session1"].Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	session, ok := transporters["b/session1"].(*p2pTransporter).sessions.Load(a.id)
+	if !ok {
+		t.Fatal("b should have a session with a")
+	}
+	select {
+	case <-session.(*peerSession).finished:
+	case <-time.After(drainTimeout):
+		t.Fatal("bye of a should be received by b before the stream is closed")
+	}
+	if err := transporters["b/session2"].Send(message(t, b, "session2"), common.TssClientId(a.id)); err != nil {
+		t.Fatal(err)
+	}
+	if received := receive(t, transporters["a/session2"]); received != "session2" {
+		t.Fatalf("session2 received a message of %s", received)
+	}
+	for _, self := range []string{"a", "b"} {
+		if err := transporters[self+"/session2"].Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// a session failing to start doesn't affect the host, its session id can be used again
+func TestSessionFailsWithoutAffectingHost(t *testing.T) {
+	home := t.TempDir()
+	a, b := newTestParty(t, home, "a"), newTestParty(t, home, "b")
+	config := a.config(home, b)
+	config.BootstrapTimeout = time.Second
+
+	// b never runs session1
+	_, err := NewSessionTransporter(a.host, "session1", nil, nil, nil, nil, nil, config)
+	if err == nil || !strings.Contains(err.Error(), "b("+b.id+")") {
+		t.Fatalf("session should fail with b not connected, got %v", err)
+	}
+	if _, err := NewSessionTransporter(a.host, "", nil, nil, nil, nil, nil, config); err == nil {
+		t.Fatal("session id should be required on a shared host")
+	}
+
+	transporters := make(chan common.Transporter, 1)
+	go func() {
+		transporter, err := NewSessionTransporter(b.host, "session1", nil, nil, nil, nil, nil, b.config(home, a))
+		if err != nil {
+			t.Error(err)
+		}
+		transporters <- transporter
+	}()
+	config.BootstrapTimeout = time.Minute
+	transporter, err := NewSessionTransporter(a.host, "session1", nil, nil, nil, nil, nil, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerTransporter := <-transporters
+	if peerTransporter == nil {
+		t.FailNow()
+	}
+	if _, err := NewSessionTransporter(a.host, "session1", nil, nil, nil, nil, nil, config); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Fatalf("a running session id should not be used by another session, got %v", err)
+	}
+	if err := transporter.Send(message(t, a, "session1"), common.TssClientId(b.id)); err != nil {
+		t.Fatal(err)
+	}
+	if received := receive(t, peerTransporter); received != "session1" {
+		t.Fatalf("session1 received a message of %s", received)
+	}
+	for _, tr := range []common.Transporter{transporter, peerTransporter} {
+		if err := tr.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -1,61 +1,59 @@
 package p2p
 
 import (
-	"sync"
+	"fmt"
+	"time"
 
 	"github.com/bnb-chain/tss-lib/v2/tss"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/bnb-chain/tss/common"
 )
 
-var once = sync.Once{}
-var registeredTransporters map[common.TssClientId]*memTransporter
-
-// in memory transporter used for testing
+// in memory transporter used for testing, see MemNet
 type memTransporter struct {
 	cid       common.TssClientId
+	net       *MemNet
 	receiveCh chan common.P2pMessageWrapper
+	controlCh chan common.ControlMessage
 }
 
 var _ common.Transporter = (*memTransporter)(nil)
 
-func NewMemTransporter(cid common.TssClientId) common.Transporter {
-	t := memTransporter{
-		cid:       cid,
-		receiveCh: make(chan common.P2pMessageWrapper, receiveChBufSize),
-	}
-	once.Do(func() {
-		registeredTransporters = make(map[common.TssClientId]*memTransporter, 0)
-	})
-
-	registeredTransporters[cid] = &t
-	return &t
-}
-
-func GetMemTransporter(cid common.TssClientId) common.Transporter {
-	return registeredTransporters[cid]
-}
-
 func (t *memTransporter) NodeKey() []byte {
 	return []byte(t.cid.String())
 }
 
 func (t *memTransporter) Broadcast(msg tss.Message) error {
 	logger.Debugf("[%s] Broadcast: %s", t.cid, msg)
-	for cid, peer := range registeredTransporters {
+	payload, err := proto.Marshal(msg.WireMsg())
+	if err != nil {
+		return fmt.Errorf("failed to encode protobuf message: %v, broadcast stop", err)
+	}
+	payload = append([]byte{MessagePrefix}, payload...)
+	if msg.GetTo() != nil {
+		for _, dest := range msg.GetTo() {
+			t.net.send(t.cid, common.TssClientId(dest.Id), payload)
+		}
+		return nil
+	}
+	t.net.mtx.Lock()
+	peers := make([]common.TssClientId, 0, len(t.net.nodes))
+	for cid := range t.net.nodes {
 		if cid != t.cid {
-			originMsg, _, _ := msg.WireBytes()
-			peer.receiveCh <- common.P2pMessageWrapper{MessageWrapperBytes: originMsg}
+			peers = append(peers, cid)
 		}
 	}
+	t.net.mtx.Unlock()
+	for _, cid := range peers {
+		t.net.send(t.cid, cid, payload)
+	}
 	return nil
 }
 
 func (t *memTransporter) Send(msg []byte, to common.TssClientId) error {
-	logger.Debugf("[%s] Sending: %x", t.cid, msg)
-	if peer, ok := registeredTransporters[to]; ok {
-		peer.receiveCh <- common.P2pMessageWrapper{MessageWrapperBytes: msg}
-	}
+	logger.Debugf("[%s] Sending to: %s", t.cid, to)
+	t.net.send(t.cid, to, msg)
 	return nil
 }
 
@@ -63,6 +61,49 @@ func (t *memTransporter) ReceiveCh() <-chan common.P2pMessageWrapper {
 	return t.receiveCh
 }
 
+// ControlCh reports messages delivered, it never reports errors, as what network loses is lost silently
+func (t *memTransporter) ControlCh() <-chan common.ControlMessage {
+	return t.controlCh
+}
+
+func (t *memTransporter) WaitForDelivery(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		pending := t.net.pending(t.cid)
+		if len(pending) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("messages are not delivered to peers within %v: %v", timeout, pending)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 func (t *memTransporter) Shutdown() error {
+	t.net.detach(t.cid)
 	return nil
 }
+
+// receive unwraps a message from network, it returns false if the link is closed before the message is received
+func (t *memTransporter) receive(from common.TssClientId, payload []byte, linkClosed <-chan struct{}) bool {
+	if len(payload) == 0 || payload[0] != MessagePrefix {
+		// i.e. the prefix is corrupted, it is dropped as the p2p transporter drops what it cannot recognize
+		logger.Errorf("[%s] dropped an unknown message from %s", t.cid, from)
+		return true
+	}
+	select {
+	case t.receiveCh <- common.P2pMessageWrapper{MessageWrapperBytes: payload[1:]}:
+		return true
+	case <-linkClosed:
+		return false
+	}
+}
+
+// reportDelivered tells consumer of ControlCh that messages up to delivered are received by to, it doesn't block
+func (t *memTransporter) reportDelivered(to common.TssClientId, delivered uint64) {
+	select {
+	case t.controlCh <- common.ControlMessage{Peer: to, Delivered: delivered}:
+	default:
+	}
+}
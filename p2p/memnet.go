@@ -0,0 +1,303 @@
+package p2p
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/bnb-chain/tss/common"
+)
+
+// how long a message chosen to be reordered waits for the next message on its link before it is delivered anyway
+const reorderWindow = 50 * time.Millisecond
+
+// LinkFaults programs how a link misbehaves, rates are probabilities (0 to 1) applied to each message.
+// The zero value is a perfect link: messages are delivered instantly, in order and exactly once.
+// Ceremonies survive delays, duplicates and reordering. As nothing is retransmitted, drops and corruption only show how
+// they fail: a dropped message stalls the ceremony until its timeout, a corrupted one fails Start of the receiving client
+// with an error as it cannot be decoded or verified (or is dropped silently if its prefix is corrupted)
+type LinkFaults struct {
+	DropRate      float64
+	Delay         time.Duration // every message is delayed this long
+	Jitter        time.Duration // plus a random delay up to this, messages keep their order on the link
+	DuplicateRate float64       // message is delivered twice
+	ReorderRate   float64       // message is delivered after the next one on the link
+	CorruptRate   float64       // a random bit of message is flipped
+}
+
+// LinkStats counts what happened to messages sent over a link
+type LinkStats struct {
+	Sent       int
+	Dropped    int // including messages dropped by partition
+	Duplicated int
+	Reordered  int
+	Corrupted  int
+	Delivered  int
+}
+
+func (s *LinkStats) add(other LinkStats) {
+	s.Sent += other.Sent
+	s.Dropped += other.Dropped
+	s.Duplicated += other.Duplicated
+	s.Reordered += other.Reordered
+	s.Corrupted += other.Corrupted
+	s.Delivered += other.Delivered
+}
+
+type memLinkKey struct {
+	from, to common.TssClientId
+}
+
+type memEnvelope struct {
+	payload []byte
+	at      time.Time // when the message should arrive
+	reorder bool
+}
+
+// a directed link, messages are delivered by a routine of its own so that a slow receiver doesn't hold up other links
+type memLink struct {
+	queue   chan memEnvelope
+	closed  chan struct{} // closed once either end is shut down
+	pending int           // messages queued but not delivered yet, guarded by MemNet.mtx
+	stats   LinkStats     // guarded by MemNet.mtx
+}
+
+// MemNet is an in memory network for testing, transporters attached to it (see Attach) exchange messages over links
+// which can be programmed to drop, delay, duplicate, reorder or corrupt messages, or be cut by partitions.
+// Like a raw network, it doesn't retransmit what is lost, so tests can see how protocols behave under adverse conditions
+type MemNet struct {
+	mtx        sync.Mutex
+	rand       *rand.Rand
+	nodes      map[common.TssClientId]*memTransporter
+	links      map[memLinkKey]*memLink
+	faults     LinkFaults // of links without faults of their own
+	linkFaults map[memLinkKey]LinkFaults
+	partitions map[common.TssClientId]int // group of each node, nodes not in any group are together
+	history    map[memLinkKey]LinkStats   // stats of links closed as either end is shut down
+}
+
+// NewMemNet creates a network whose faults are drawn from seed, so that a failing test can be reproduced
+func NewMemNet(seed int64) *MemNet {
+	return &MemNet{
+		rand:       rand.New(rand.NewSource(seed)),
+		nodes:      make(map[common.TssClientId]*memTransporter),
+		links:      make(map[memLinkKey]*memLink),
+		linkFaults: make(map[memLinkKey]LinkFaults),
+		partitions: make(map[common.TssClientId]int),
+		history:    make(map[memLinkKey]LinkStats),
+	}
+}
+
+// Attach creates a transporter of cid on the network, a client id can only be attached once until its transporter is shut down
+func (n *MemNet) Attach(cid common.TssClientId) common.Transporter {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	if _, ok := n.nodes[cid]; ok {
+		common.Panic(fmt.Errorf("%s is already attached to network", cid))
+	}
+	t := &memTransporter{
+		cid:       cid,
+		net:       n,
+		receiveCh: make(chan common.P2pMessageWrapper, receiveChBufSize),
+		controlCh: make(chan common.ControlMessage, controlChBufSize),
+	}
+	n.nodes[cid] = t
+	return t
+}
+
+// SetFaults programs every link that has no faults of its own (see SetLinkFaults)
+func (n *MemNet) SetFaults(faults LinkFaults) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.faults = faults
+}
+
+// SetLinkFaults programs the link from one node to another, the opposite direction is not affected
+func (n *MemNet) SetLinkFaults(from, to common.TssClientId, faults LinkFaults) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.linkFaults[memLinkKey{from, to}] = faults
+}
+
+// Partition splits the network, messages between nodes of different groups are dropped until Heal.
+// Nodes not in any group form a group of their own
+func (n *MemNet) Partition(groups ...[]common.TssClientId) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.partitions = make(map[common.TssClientId]int)
+	for idx, group := range groups {
+		for _, cid := range group {
+			n.partitions[cid] = idx + 1
+		}
+	}
+}
+
+// Heal removes partitions and all faults programmed
+func (n *MemNet) Heal() {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.faults = LinkFaults{}
+	n.linkFaults = make(map[memLinkKey]LinkFaults)
+	n.partitions = make(map[common.TssClientId]int)
+}
+
+// Stats tells what happened to messages sent from one node to another so far, including before either of them is shut down
+func (n *MemNet) Stats(from, to common.TssClientId) LinkStats {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	stats := n.history[memLinkKey{from, to}]
+	if l, ok := n.links[memLinkKey{from, to}]; ok {
+		stats.add(l.stats)
+	}
+	return stats
+}
+
+// send decides the fate of msg on link from -> to and queues what survives, it blocks if too many messages are pending on the link
+func (n *MemNet) send(from, to common.TssClientId, msg []byte) {
+	n.mtx.Lock()
+	key := memLinkKey{from, to}
+	l := n.link(key)
+	if l == nil {
+		// peer is not attached (yet) or has been shut down
+		n.mtx.Unlock()
+		return
+	}
+	faults, ok := n.linkFaults[key]
+	if !ok {
+		faults = n.faults
+	}
+	l.stats.Sent++
+	if n.partitions[from] != n.partitions[to] || n.happens(faults.DropRate) {
+		l.stats.Dropped++
+		n.mtx.Unlock()
+		return
+	}
+	copies := 1
+	if n.happens(faults.DuplicateRate) {
+		l.stats.Duplicated++
+		copies++
+	}
+	envelopes := make([]memEnvelope, 0, copies)
+	for i := 0; i < copies; i++ {
+		payload := append([]byte(nil), msg...) // sender might reuse msg, and copies are corrupted independently
+		if len(payload) > 0 && n.happens(faults.CorruptRate) {
+			l.stats.Corrupted++
+			bit := n.rand.Intn(len(payload) * 8)
+			payload[bit/8] ^= 1 << uint(bit%8)
+		}
+		delay := faults.Delay
+		if faults.Jitter > 0 {
+			delay += time.Duration(n.rand.Int63n(int64(faults.Jitter)))
+		}
+		reorder := n.happens(faults.ReorderRate)
+		if reorder {
+			l.stats.Reordered++
+		}
+		envelopes = append(envelopes, memEnvelope{payload, time.Now().Add(delay), reorder})
+	}
+	l.pending += copies
+	n.mtx.Unlock()
+
+	for _, envelope := range envelopes {
+		select {
+		case l.queue <- envelope:
+		case <-l.closed:
+			return
+		}
+	}
+}
+
+// link returns link of key, it is created once both ends are attached. Caller should hold mtx
+func (n *MemNet) link(key memLinkKey) *memLink {
+	if l, ok := n.links[key]; ok {
+		return l
+	}
+	if n.nodes[key.from] == nil || n.nodes[key.to] == nil {
+		return nil
+	}
+	l := &memLink{
+		queue:  make(chan memEnvelope, sendQueueSize),
+		closed: make(chan struct{}),
+	}
+	n.links[key] = l
+	go n.deliverRoutine(key, l, n.nodes[key.to])
+	return l
+}
+
+// happens draws whether an event of rate happens. Caller should hold mtx
+func (n *MemNet) happens(rate float64) bool {
+	return rate > 0 && n.rand.Float64() < rate
+}
+
+func (n *MemNet) deliverRoutine(key memLinkKey, l *memLink, to *memTransporter) {
+	var held *memEnvelope
+	for {
+		var flush <-chan time.Time
+		if held != nil {
+			flush = time.After(reorderWindow)
+		}
+		select {
+		case envelope := <-l.queue:
+			time.Sleep(time.Until(envelope.at))
+			if envelope.reorder && held == nil {
+				held = &envelope
+				continue
+			}
+			n.deliver(key, l, to, envelope.payload)
+			if held != nil {
+				n.deliver(key, l, to, held.payload)
+				held = nil
+			}
+		case <-flush:
+			n.deliver(key, l, to, held.payload)
+			held = nil
+		case <-l.closed:
+			return
+		}
+	}
+}
+
+func (n *MemNet) deliver(key memLinkKey, l *memLink, to *memTransporter, payload []byte) {
+	if !to.receive(key.from, payload, l.closed) {
+		return
+	}
+	n.mtx.Lock()
+	l.pending--
+	l.stats.Delivered++
+	delivered := uint64(l.stats.Delivered)
+	from := n.nodes[key.from]
+	n.mtx.Unlock()
+	if from != nil {
+		from.reportDelivered(key.to, delivered)
+	}
+}
+
+// pending tells how many messages sent by cid are not delivered yet, per peer
+func (n *MemNet) pending(cid common.TssClientId) map[common.TssClientId]int {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	pending := make(map[common.TssClientId]int)
+	for key, l := range n.links {
+		if key.from == cid && l.pending > 0 {
+			pending[key.to] = l.pending
+		}
+	}
+	return pending
+}
+
+// detach removes cid from network, messages in flight from or to it are dropped. Stats of its links are kept
+func (n *MemNet) detach(cid common.TssClientId) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	delete(n.nodes, cid)
+	for key, l := range n.links {
+		if key.from == cid || key.to == cid {
+			close(l.closed)
+			delete(n.links, key)
+			stats := n.history[key]
+			stats.add(l.stats)
+			n.history[key] = stats
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package p2p
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/bits"
+	"testing"
+	"time"
+
+	"github.com/bnb-chain/tss/common"
+)
+
+// faults are drawn from a fixed seed so that failures can be reproduced
+const testSeed = 20261019
+
+func newTestNet(cids ...common.TssClientId) (*MemNet, map[common.TssClientId]common.Transporter) {
+	net := NewMemNet(testSeed)
+	transporters := make(map[common.TssClientId]common.Transporter)
+	for _, cid := range cids {
+		transporters[cid] = net.Attach(cid)
+	}
+	return net, transporters
+}
+
+// numbered is a message carrying n, so that tests can tell the order messages are received
+func numbered(n uint32) []byte {
+	msg := make([]byte, 5)
+	msg[0] = MessagePrefix
+	binary.BigEndian.PutUint32(msg[1:], n)
+	return msg
+}
+
+// sendNumbered sends count numbered messages and waits until what survives is delivered
+func sendNumbered(t *testing.T, from common.Transporter, to common.TssClientId, count int) {
+	for i := 0; i < count; i++ {
+		if err := from.Send(numbered(uint32(i)), to); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := from.WaitForDelivery(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// drain returns messages received so far
+func drain(transporter common.Transporter) [][]byte {
+	var received [][]byte
+	for {
+		select {
+		case msg := <-transporter.ReceiveCh():
+			received = append(received, msg.MessageWrapperBytes)
+		default:
+			return received
+		}
+	}
+}
+
+func numbers(t *testing.T, received [][]byte) []uint32 {
+	ns := make([]uint32, 0, len(received))
+	for _, msg := range received {
+		if len(msg) != 4 {
+			t.Fatalf("unexpected message %x", msg)
+		}
+		ns = append(ns, binary.BigEndian.Uint32(msg))
+	}
+	return ns
+}
+
+func TestMemNetPerfectLink(t *testing.T) {
+	net, transporters := newTestNet("a", "b")
+	sendNumbered(t, transporters["a"], "b", 100)
+	for i, n := range numbers(t, drain(transporters["b"])) {
+		if n != uint32(i) {
+			t.Fatalf("message %d is received at %d", n, i)
+		}
+	}
+	if stats := net.Stats("a", "b"); stats != (LinkStats{Sent: 100, Delivered: 100}) {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestMemNetDrop(t *testing.T) {
+	net, transporters := newTestNet("a", "b")
+	net.SetLinkFaults("a", "b", LinkFaults{DropRate: 0.5})
+	sendNumbered(t, transporters["a"], "b", 100)
+	sendNumbered(t, transporters["b"], "a", 100)
+
+	received := numbers(t, drain(transporters["b"]))
+	stats := net.Stats("a", "b")
+	if stats.Dropped == 0 || stats.Delivered == 0 || stats.Dropped+stats.Delivered != stats.Sent || len(received) != stats.Delivered {
+		t.Fatalf("about half of messages should be dropped, stats: %+v, received: %d", stats, len(received))
+	}
+	for i := 1; i < len(received); i++ {
+		if received[i] <= received[i-1] {
+			t.Fatalf("survivors should keep their order: %v", received)
+		}
+	}
+	// the same seed drops the same messages
+	again, againTransporters := newTestNet("a", "b")
+	again.SetLinkFaults("a", "b", LinkFaults{DropRate: 0.5})
+	sendNumbered(t, againTransporters["a"], "b", 100)
+	if againReceived := numbers(t, drain(againTransporters["b"])); len(againReceived) != len(received) {
+		t.Fatalf("faults should be reproduced by the seed, received %d then %d", len(received), len(againReceived))
+	}
+	// faults of a link don't affect the opposite direction
+	if stats := net.Stats("b", "a"); stats != (LinkStats{Sent: 100, Delivered: 100}) {
+		t.Fatalf("opposite link should be perfect: %+v", stats)
+	}
+}
+
+func TestMemNetDelay(t *testing.T) {
+	net, transporters := newTestNet("a", "b")
+	net.SetFaults(LinkFaults{Delay: 100 * time.Millisecond, Jitter: 50 * time.Millisecond})
+	started := time.Now()
+	sendNumbered(t, transporters["a"], "b", 20)
+	if elapsed := time.Since(started); elapsed < 100*time.Millisecond {
+		t.Fatalf("messages should be delayed at least 100ms, delivered in %v", elapsed)
+	}
+	// jitter doesn't reorder messages on a link
+	for i, n := range numbers(t, drain(transporters["b"])) {
+		if n != uint32(i) {
+			t.Fatalf("message %d is received at %d", n, i)
+		}
+	}
+}
+
+func TestMemNetDuplicate(t *testing.T) {
+	net, transporters := newTestNet("a", "b")
+	net.SetFaults(LinkFaults{DuplicateRate: 1})
+	sendNumbered(t, transporters["a"], "b", 10)
+	received := numbers(t, drain(transporters["b"]))
+	if len(received) != 20 {
+		t.Fatalf("every message should be received twice, got %v", received)
+	}
+	for i, n := range received {
+		if n != uint32(i/2) {
+			t.Fatalf("message %d is received at %d", n, i)
+		}
+	}
+	if stats := net.Stats("a", "b"); stats != (LinkStats{Sent: 10, Duplicated: 10, Delivered: 20}) {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestMemNetReorder(t *testing.T) {
+	net, transporters := newTestNet("a", "b")
+	net.SetFaults(LinkFaults{ReorderRate: 1})
+	// a message is held until the next one is delivered, the last one is delivered after reorderWindow
+	sendNumbered(t, transporters["a"], "b", 5)
+	received := numbers(t, drain(transporters["b"]))
+	expected := []uint32{1, 0, 3, 2, 4}
+	if len(received) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, received)
+	}
+	for i := range expected {
+		if received[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, received)
+		}
+	}
+	if stats := net.Stats("a", "b"); stats.Reordered != 5 || stats.Delivered != 5 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestMemNetCorrupt(t *testing.T) {
+	net, transporters := newTestNet("a", "b")
+	net.SetFaults(LinkFaults{CorruptRate: 1})
+	sent := append([]byte{MessagePrefix}, make([]byte, 32)...)
+	for i := 0; i < 50; i++ {
+		if err := transporters["a"].Send(sent, "b"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := transporters["a"].WaitForDelivery(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if sent[1] != 0 {
+		t.Fatal("message of sender should not be corrupted")
+	}
+	// a message with corrupted prefix is dropped by receiver, others have exactly one bit flipped
+	received := drain(transporters["b"])
+	for _, msg := range received {
+		flipped := 0
+		for _, b := range msg {
+			flipped += bits.OnesCount8(b)
+		}
+		if flipped != 1 {
+			t.Fatalf("one bit should be flipped, got %x", msg)
+		}
+	}
+	if stats := net.Stats("a", "b"); stats.Corrupted != 50 || stats.Delivered != 50 || len(received) == 0 || len(received) > 50 {
+		t.Fatalf("unexpected stats: %+v, received: %d", stats, len(received))
+	}
+}
+
+func TestMemNetPartition(t *testing.T) {
+	net, transporters := newTestNet("a", "b", "c")
+	net.SetLinkFaults("a", "c", LinkFaults{DuplicateRate: 1})
+	// c is not in any group, so it is cut from both a and b
+	net.Partition([]common.TssClientId{"a", "b"})
+	for _, to := range []common.TssClientId{"b", "c"} {
+		if err := transporters["a"].Send(numbered(0), to); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := transporters["a"].WaitForDelivery(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if received := drain(transporters["b"]); len(received) != 1 {
+		t.Fatalf("b should receive from a in the same group, got %d", len(received))
+	}
+	if received := drain(transporters["c"]); len(received) != 0 {
+		t.Fatalf("c should not receive from a in another group, got %d", len(received))
+	}
+
+	// healing removes partitions and faults
+	net.Heal()
+	if err := transporters["a"].Send(numbered(1), "c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := transporters["a"].WaitForDelivery(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if received := drain(transporters["c"]); len(received) != 1 || !bytes.Equal(received[0], numbered(1)[1:]) {
+		t.Fatalf("c should receive from a once healed, got %x", received)
+	}
+	if stats := net.Stats("a", "c"); stats != (LinkStats{Sent: 2, Dropped: 1, Delivered: 1}) {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	// a shut down node is detached, what is sent to it is not counted, and stats so far are kept
+	if err := transporters["c"].Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if err := transporters["a"].Send(numbered(2), "c"); err != nil {
+		t.Fatal(err)
+	}
+	if stats := net.Stats("a", "c"); stats != (LinkStats{Sent: 2, Dropped: 1, Delivered: 1}) {
+		t.Fatalf("stats of a detached node should be kept: %+v", stats)
+	}
+}
@@ -17,8 +17,12 @@ func (nn *cmNotifee) Connected(n network.Network, c network.Conn) {
 
 func (nn *cmNotifee) Disconnected(n network.Network, c network.Conn) {
 	logger.Debugf("[Disconnected] %s (%s) dir: %d", c.RemotePeer().Pretty(), c.RemoteMultiaddr().String(), c.Stat().Direction)
-	//nn.t.streams.Delete(c.RemotePeer().Pretty())
-	// TODO: trigger reconnect
+	// reset party stream over this connection, so that read routine starts reconnection at once
+	if session, ok := nn.t.sessions.Load(c.RemotePeer().Pretty()); ok {
+		if stream, _ := session.(*peerSession).current(); stream.Conn() == c {
+			stream.Reset()
+		}
+	}
 }
 
 func (nn *cmNotifee) Listen(n network.Network, addr multiaddr.Multiaddr) {}
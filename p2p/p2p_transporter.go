@@ -3,34 +3,21 @@ package p2p
 import (
 	"context"
 	"crypto/sha256"
-	"encoding/binary"
 	"encoding/gob"
-	"encoding/hex"
 	"fmt"
-	"github.com/ipfs/go-datastore"
-	"github.com/libp2p/go-libp2p"
-	"github.com/libp2p/go-yamux"
 	"github.com/multiformats/go-multiaddr"
-	"io/ioutil"
-	"os"
-	"path"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/bnb-chain/tss-lib/v2/tss"
-	relay "github.com/libp2p/go-libp2p-circuit"
-	ifconnmgr "github.com/libp2p/go-libp2p-core/connmgr"
-	"github.com/libp2p/go-libp2p-core/crypto"
 	"github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/network"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/libp2p/go-libp2p-core/peerstore"
 	"github.com/libp2p/go-libp2p-core/protocol"
 	libp2pdht "github.com/libp2p/go-libp2p-kad-dht"
-	opts "github.com/libp2p/go-libp2p-kad-dht/opts"
-	"github.com/libp2p/go-libp2p-peerstore/pstoremem"
 	swarm "github.com/libp2p/go-libp2p-swarm"
 	"google.golang.org/protobuf/proto"
 
@@ -42,20 +29,33 @@ const (
 	bootstrapProtocolId = "/tss/bootstrap/0.0.1"
 	loggerName          = "trans"
 	receiveChBufSize    = 500
+	controlChBufSize    = 100
 )
 
 const (
-	MessagePrefix     = 0x1
-	HashMessagePrefix = 0x2
+	MessagePrefix          = 0x1
+	HashMessagePrefix      = 0x2 // digest of broadcasts received in a round, see BroadcastDigest
+	AgreementMessagePrefix = 0x3
+	SequencedMessagePrefix = 0x4 // wraps other messages with a sequence once the protocol starts, see peerSession
+	AckMessagePrefix       = 0x5
+	ByeMessagePrefix       = 0x6
+	SignedMessagePrefix    = 0x7 // broadcast tss message signed by sender, see SignedBroadcast
+	BlameMessagePrefix     = 0x8
+	EchoMessagePrefix      = 0x9 // reliable broadcast, see reliableBroadcast
+	ReadyMessagePrefix     = 0xa
 )
 
 // P2P implementation of Transporter
 type p2pTransporter struct {
-	ifconnmgr.NullConnMgr
-
 	nodeKey []byte
 	ctx     context.Context
 
+	shared            *Host
+	ownHost           bool   // whether the host is closed on shutdown, false if it is shared with other sessions
+	sessionId         string // empty if the host is not shared
+	partyProtocol     protocol.ID
+	bootstrapProtocol protocol.ID
+
 	// for bootstrap
 	bootstrapper *common.Bootstrapper
 
@@ -63,24 +63,34 @@ type p2pTransporter struct {
 	params        *tss.Parameters
 	regroupParams *tss.ReSharingParameters
 
-	pathToRouteTable      string
+	// committee we are going to run the protocol with, all peers should agree on it before the protocol starts
+	agreement *CommitteeAgreement
+
+	pathToVault           string
 	expectedPeers         []peer.ID
-	streams               sync.Map // map[peer.ID.Pretty()]network.Stream
-	encoders              sync.Map // map[common.TssClientId]*gob.Encoder
-	numOfStreams          int32    // atomic int of len(streams)
-	numOfBootstrapStreams int32    // atomic int of len(bootstrapStreams)
-	bootstrapPeers        []multiaddr.Multiaddr
-	relayPeers            []multiaddr.Multiaddr
+	monikers              map[string]string // party id -> moniker of us and peers, for logs and evidence
+	streams               sync.Map          // map[peer.ID.Pretty()]network.Stream
+	sessions              sync.Map          // map[peer.ID.Pretty()]*peerSession, set up once all peers agree on the committee
+	encoders              sync.Map          // map[common.TssClientId]*gob.Encoder
+	numOfStreams          int32             // atomic int of len(streams)
+	numOfBootstrapStreams int32             // atomic int of len(bootstrapStreams)
+	connectTimeout        time.Duration     // how long to wait for streams with all expected peers, 0 means forever
 	notifee               network.Notifiee
+	codec                 common.FrameCodec // frames party streams, maximum message size is configurable
 
 	// sanity check related field
 	broadcastSanityCheck bool
 	sanityCheckMtx       *sync.Mutex
-	ioMtx                *sync.Mutex
-	pendingCheckHashMsg  map[p2pMessageKey]*P2PMessageWithHash   // guarded by sanityCheckMtx
-	receivedPeersHashMsg map[p2pMessageKey][]*P2PMessageWithHash // guarded by sanityCheckMtx
+	broadcastRounds      map[string]*broadcastRound // message type -> round, guarded by sanityCheckMtx
+	verifiedRounds       map[string]bool            // message types delivered, guarded by sanityCheckMtx
+	blameOnce            sync.Once
+
+	reliableBroadcast    bool
+	reliableBroadcastMtx *sync.Mutex
+	reliableBroadcasts   map[p2pMessageKey]*reliableBroadcast // guarded by reliableBroadcastMtx
 
 	receiveCh chan common.P2pMessageWrapper
+	controlCh chan common.ControlMessage
 	host      host.Host
 
 	closed chan bool
@@ -88,105 +98,136 @@ type p2pTransporter struct {
 
 type p2pMessageKey string
 
-func keyOf(m *P2PMessageWithHash) p2pMessageKey {
-	return p2pMessageKey(fmt.Sprintf("%s%x", m.From, m.Hash))
-}
-
-var _ ifconnmgr.ConnManager = (*p2pTransporter)(nil)
 var _ common.Transporter = (*p2pTransporter)(nil)
 
 // Constructor of p2pTransporter
 // signers indicate which peers within config.ExpectedPeer should be connected (non-empty for regroup and sign, empty for keygen)
-// Once this is done, the transportation is ready to use
+// agreement is exchanged with all peers once they are connected, nil for bootstrapping
+// Once this is done, the transportation is ready to use. Otherwise an error tells why: peers are not connected within
+// config.BootstrapTimeout, bootstrapping times out (*common.BootstrapTimeoutError) or peers disagree on the committee (*AgreementError)
 func NewP2PTransporter(
-	home, vault, nodeId string,
 	bootstrapper *common.Bootstrapper,
 	params *tss.Parameters,
 	regroupParams *tss.ReSharingParameters,
+	agreement *CommitteeAgreement,
+	signers map[string]int,
+	config *common.TssConfig) (common.Transporter, error) {
+	t, err := newP2PTransporter(NewHost(config.Home, config.Vault, &config.P2PConfig), true, "", bootstrapper, params, regroupParams, agreement, signers, config)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// NewSessionTransporter runs a session over a host shared with other sessions, the host is kept open after Shutdown.
+// sessionId should be the same for all parties of the session (i.e. derived from channel id) and unique among sessions running on the host
+func NewSessionTransporter(
+	h *Host,
+	sessionId string,
+	bootstrapper *common.Bootstrapper,
+	params *tss.Parameters,
+	regroupParams *tss.ReSharingParameters,
+	agreement *CommitteeAgreement,
 	signers map[string]int,
-	config *common.P2PConfig) common.Transporter {
+	config *common.TssConfig) (common.Transporter, error) {
+	if sessionId == "" {
+		return nil, fmt.Errorf("session id is required to share a host")
+	}
+	t, err := newP2PTransporter(h, false, sessionId, bootstrapper, params, regroupParams, agreement, signers, config)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func newP2PTransporter(
+	h *Host,
+	ownHost bool,
+	sessionId string,
+	bootstrapper *common.Bootstrapper,
+	params *tss.Parameters,
+	regroupParams *tss.ReSharingParameters,
+	agreement *CommitteeAgreement,
+	signers map[string]int,
+	config *common.TssConfig) (*p2pTransporter, error) {
 	t := &p2pTransporter{}
 
-	t.ctx = context.Background()
+	t.ctx = h.ctx
+	t.shared = h
+	t.ownHost = ownHost
+	t.sessionId = sessionId
+	t.partyProtocol = sessionProtocol(partyProtocolId, sessionId)
+	t.bootstrapProtocol = sessionProtocol(bootstrapProtocolId, sessionId)
 	if bootstrapper != nil {
 		t.bootstrapper = bootstrapper
 	}
-	t.pathToRouteTable = path.Join(home, vault, "rt/")
-	ps := pstoremem.NewPeerstore()
-	t.setExpectedPeers(nodeId, signers, ps, config) // t.expectedPeers will be updated in this method
-	t.bootstrapPeers = config.BootstrapPeers
-	// TODO: relay addr need further confirm
-	// The correct address should be /p2p-circuit/p2p/<dest ID> rather than /p2p-circuit/p2p/<relay ID>
-	for _, relayPeerAddr := range config.RelayPeers {
-		relayPeerInfo, err := peer.AddrInfoFromP2pAddr(relayPeerAddr)
-		if err != nil {
-			common.Panic(err)
+	t.params = params
+	t.regroupParams = regroupParams
+	t.agreement = agreement
+	t.pathToVault = h.pathToVault
+	t.nodeKey = h.nodeKey
+	t.host = h.Host
+	t.connectTimeout = config.BootstrapTimeout
+	// t.expectedPeers will be updated in this method
+	if err := t.setExpectedPeers(config.Id.String(), signers, h.Peerstore(), &config.P2PConfig); err != nil {
+		if t.ownHost {
+			h.Close()
 		}
-		relayAddr, err := multiaddr.NewMultiaddr("/p2p-circuit/p2p/" + relayPeerInfo.ID.Pretty())
-		if err != nil {
-			common.Panic(err)
+		return nil, err
+	}
+	t.monikers = map[string]string{config.Id.String(): config.Moniker}
+	for _, peers := range [][]string{config.ExpectedPeers, config.ExpectedNewPeers} {
+		for _, p := range peers {
+			t.monikers[string(GetClientIdFromExpectedPeers(p))] = GetMonikerFromExpectedPeers(p)
 		}
-		t.relayPeers = append(t.relayPeers, relayAddr)
 	}
 
 	t.notifee = &cmNotifee{t}
-	t.broadcastSanityCheck = config.BroadcastSanityCheck
+	broadcastMode, err := config.GetBroadcastMode()
+	if err != nil {
+		if t.ownHost {
+			h.Close()
+		}
+		return nil, err
+	}
+	t.broadcastSanityCheck = broadcastMode == common.BroadcastModeSanityCheck
 	if t.broadcastSanityCheck {
 		t.sanityCheckMtx = &sync.Mutex{}
-		t.pendingCheckHashMsg = make(map[p2pMessageKey]*P2PMessageWithHash)
-		t.receivedPeersHashMsg = make(map[p2pMessageKey][]*P2PMessageWithHash)
+		t.broadcastRounds = make(map[string]*broadcastRound)
+		t.verifiedRounds = make(map[string]bool)
+	}
+	t.reliableBroadcast = broadcastMode == common.BroadcastModeReliable
+	if t.reliableBroadcast {
+		t.reliableBroadcastMtx = &sync.Mutex{}
+		t.reliableBroadcasts = make(map[p2pMessageKey]*reliableBroadcast)
 	}
-	t.ioMtx = &sync.Mutex{}
+	t.codec = common.FrameCodec{MaxSize: config.MaxMessageSize}
 
 	t.receiveCh = make(chan common.P2pMessageWrapper, receiveChBufSize)
-	// load private key of node id
-	var privKey crypto.PrivKey
-	pathToNodeKey := path.Join(home, vault, "node_key")
-	if _, err := os.Stat(pathToNodeKey); err == nil {
-		bytes, err := ioutil.ReadFile(pathToNodeKey)
-		if err != nil {
-			common.Panic(err)
-		}
-		privKey, err = crypto.UnmarshalPrivateKey(bytes)
-		if err != nil {
-			common.Panic(err)
-		}
-		t.nodeKey = bytes
-	}
+	t.controlCh = make(chan common.ControlMessage, controlChBufSize)
+	t.closed = make(chan bool)
 
-	addr, err := multiaddr.NewMultiaddr(config.ListenAddr)
-	if err != nil {
-		common.Panic(err)
-	}
-
-	host, err := libp2p.New(
-		t.ctx,
-		libp2p.Peerstore(ps),
-		libp2p.ConnectionManager(t),
-		libp2p.ListenAddrs(addr),
-		libp2p.Identity(privKey),
-		libp2p.EnableRelay(relay.OptDiscovery),
-		libp2p.NATPortMap(), // actually I cannot find a case that NATPortMap can help, but in case some edge case, created it to save relay server performance
-	)
-	if err != nil {
-		common.Panic(err)
+	if err := h.register(sessionId, t); err != nil {
+		if t.ownHost {
+			h.Close()
+		}
+		return nil, err
 	}
-	host.SetStreamHandler(partyProtocolId, t.handleStream)
-	host.SetStreamHandler(bootstrapProtocolId, t.handleSigner)
-	t.host = host
-	t.closed = make(chan bool)
-	logger.Debug("Host created. We are:", host.ID())
-	logger.Debug("listening on:", host.Addrs())
+	h.Network().Notify(t.notifee)
+	h.SetStreamHandler(t.partyProtocol, t.handleStream)
+	h.SetStreamHandler(t.bootstrapProtocol, t.handleSigner)
 	logger.Info("waiting peers connection...")
 
-	dht := t.setupDHTClient()
 	if bootstrapper != nil {
-		t.initBootstrapConnection(dht)
+		err = t.initBootstrapConnection(h.dht)
 	} else {
-		t.initConnection(dht)
+		err = t.initConnection(h.dht)
 	}
-
-	return t
+	if err != nil {
+		t.abort()
+		return nil, err
+	}
+	return t, nil
 }
 
 func (t *p2pTransporter) NodeKey() []byte {
@@ -195,7 +236,25 @@ func (t *p2pTransporter) NodeKey() []byte {
 
 func (t *p2pTransporter) Broadcast(msg tss.Message) error {
 	logger.Debug("Broadcast: ", msg)
-	var err error
+	payload, err := proto.Marshal(msg.WireMsg())
+	if err != nil {
+		return fmt.Errorf("failed to encode protobuf message: %v, broadcast stop", err)
+	}
+	if msg.IsBroadcast() {
+		// peers put hashes of what they received from us in their digests in broadcast sanity check, our signature proves what we actually sent
+		key, err := broadcastKey(t.host.ID().Pretty(), payload)
+		if err != nil {
+			return fmt.Errorf("failed to identify broadcast: %v, broadcast stop", err)
+		}
+		hash := sha256.Sum256(payload)
+		payload, err = proto.Marshal(&SignedBroadcast{Msg: payload, Signature: t.sign(broadcastSigningBytes(key, hash[:]))})
+		if err != nil {
+			return fmt.Errorf("failed to encode signed broadcast: %v, broadcast stop", err)
+		}
+		payload = append([]byte{SignedMessagePrefix}, payload...)
+	} else {
+		payload = append([]byte{MessagePrefix}, payload...)
+	}
 	t.streams.Range(func(to, stream interface{}) bool {
 		shouldSend := false
 		if msg.GetTo() == nil {
@@ -209,12 +268,6 @@ func (t *p2pTransporter) Broadcast(msg tss.Message) error {
 			}
 		}
 		if shouldSend {
-			payload, e := proto.Marshal(msg.WireMsg())
-			if e != nil {
-				err = fmt.Errorf("failed to encode protobuf message: %v, broadcast stop", err)
-				return false
-			}
-			payload = append([]byte{MessagePrefix}, payload...)
 			if e := t.Send(payload, common.TssClientId(to.(string))); e != nil {
 				err = e
 				return false
@@ -226,24 +279,20 @@ func (t *p2pTransporter) Broadcast(msg tss.Message) error {
 }
 
 func (t *p2pTransporter) Send(msg []byte, to common.TssClientId) error {
-	t.ioMtx.Lock()
-	defer t.ioMtx.Unlock()
-
 	logger.Debugf("Sending to: %s", to)
-	// TODO: stream.Write should be protected by their lock?
+	if session, ok := t.sessions.Load(to.String()); ok {
+		return session.(*peerSession).send(msg)
+	}
+
+	// only the agreement is sent before sessions are set up, one message to each peer, so writes to a stream don't interleave
 	stream, ok := t.streams.Load(to.String())
 	if ok && stream != nil {
-		messageLength := int32(len(msg))
-		if err := binary.Write(stream.(network.Stream), binary.BigEndian, &messageLength); err != nil {
-			return err
-		}
-		if n, err := stream.(network.Stream).Write(msg); err != nil {
+		if err := writeFrame(t.codec, stream.(network.Stream), msg); err != nil {
 			return err
-		} else {
-			logger.Debugf("Send to: %s, bytes: %d, actual send: %d, Via (memory addr of stream): %p", to, messageLength, n, stream)
 		}
+		logger.Debugf("Send to: %s, bytes: %d, Via (memory addr of stream): %p", to, len(msg), stream)
 	} else {
-		logger.Errorf("Cannot resolve stream for peer: %s", to.String())
+		return fmt.Errorf("cannot resolve stream for peer: %s", to.String())
 	}
 	return nil
 }
@@ -252,14 +301,78 @@ func (t p2pTransporter) ReceiveCh() <-chan common.P2pMessageWrapper {
 	return t.receiveCh
 }
 
-func (t p2pTransporter) Shutdown() (err error) {
+func (t *p2pTransporter) ControlCh() <-chan common.ControlMessage {
+	return t.controlCh
+}
+
+func (t *p2pTransporter) Shutdown() (err error) {
 	logger.Info("Closing p2ptransporter")
 
-	if err := t.host.Close(); err != nil {
-		return err
+	if err := t.WaitForDelivery(drainTimeout); err != nil {
+		logger.Warningf("shutdown before messages are delivered: %v", err)
 	}
+	t.sessions.Range(func(_, session interface{}) bool {
+		session.(*peerSession).bye()
+		return true
+	})
+	// closed before host, so that read routines don't take closed streams as dropped
+	close(t.closed)
+	t.host.RemoveStreamHandler(t.partyProtocol)
+	t.host.RemoveStreamHandler(t.bootstrapProtocol)
+	t.host.Network().StopNotify(t.notifee)
+	t.shared.unregister(t.sessionId)
+	if t.ownHost {
+		return t.host.Close()
+	}
+	// other sessions go on over the host, only streams of this session are closed. Resetting right after bye might
+	// discard it, so we only close our side, read routines reset streams once peers close theirs (or after drainTimeout)
+	t.sessions.Range(func(_, session interface{}) bool {
+		session.(*peerSession).closeWrite()
+		return true
+	})
+	go func() {
+		time.Sleep(drainTimeout)
+		t.resetStreams()
+	}()
+	return nil
+}
+
+// abort releases what a transporter failing to connect its peers holds, no peer session has been set up yet
+func (t *p2pTransporter) abort() {
 	close(t.closed)
-	return
+	t.host.RemoveStreamHandler(t.partyProtocol)
+	t.host.RemoveStreamHandler(t.bootstrapProtocol)
+	t.host.Network().StopNotify(t.notifee)
+	t.shared.unregister(t.sessionId)
+	t.resetStreams()
+	if t.ownHost {
+		if err := t.host.Close(); err != nil {
+			logger.Warningf("failed to close host: %v", err)
+		}
+	}
+}
+
+// resetStreams resets streams of this session, whether they are turned into peer sessions or not
+func (t *p2pTransporter) resetStreams() {
+	t.streams.Range(func(_, stream interface{}) bool {
+		stream.(network.Stream).Reset()
+		return true
+	})
+	t.sessions.Range(func(_, session interface{}) bool {
+		if stream, _ := session.(*peerSession).current(); stream != nil {
+			stream.Reset()
+		}
+		return true
+	})
+}
+
+func (t *p2pTransporter) isClosed() bool {
+	select {
+	case <-t.closed:
+		return true
+	default:
+		return false
+	}
 }
 
 func (t p2pTransporter) closeStream(key, stream interface{}) bool {
@@ -273,258 +386,253 @@ func (t p2pTransporter) closeStream(key, stream interface{}) bool {
 	return true
 }
 
-// implementation of ConnManager
-
-func (t *p2pTransporter) Notifee() network.Notifiee {
-	return t.notifee
-}
-
 // implementation of
 
 func (t *p2pTransporter) handleStream(stream network.Stream) {
 	pid := stream.Conn().RemotePeer().Pretty()
+	if !t.acceptStream(stream) {
+		return
+	}
 	logger.Infof("Connected to: %s(%s)", pid, stream.Protocol())
 
+	if session, ok := t.sessions.Load(pid); ok {
+		// peer redialed us after the stream was dropped
+		session.(*peerSession).replace(stream)
+		return
+	}
 	if _, loaded := t.streams.LoadOrStore(pid, stream); !loaded {
 		t.encoders.Store(common.TssClientId(pid), gob.NewEncoder(stream))
 		atomic.AddInt32(&t.numOfStreams, 1)
 	}
 }
 
+// acceptStream resets stream from a peer not expected by this session. Connections from unknown peers are refused by host,
+// but a peer could still be connected for another session sharing the host, or be a bootstrap/relay peer
+func (t *p2pTransporter) acceptStream(stream network.Stream) bool {
+	pid := stream.Conn().RemotePeer()
+	if t.isExpected(pid) {
+		return true
+	}
+	securityLogger.Warningf("refused %s stream from unexpected peer %s (%s)", stream.Protocol(), pid.Pretty(), stream.Conn().RemoteMultiaddr())
+	stream.Reset()
+	return false
+}
+
+func (t *p2pTransporter) isExpected(pid peer.ID) bool {
+	for _, expected := range t.expectedPeers {
+		if expected == pid {
+			return true
+		}
+	}
+	return false
+}
+
+// handleProtocolStream handles a stream we open as if the peer opened it
+func (t *p2pTransporter) handleProtocolStream(protocolId protocol.ID, stream network.Stream) {
+	switch protocolId {
+	case t.partyProtocol:
+		t.handleStream(stream)
+	case t.bootstrapProtocol:
+		t.handleSigner(stream)
+	}
+}
+
 func (t *p2pTransporter) handleSigner(stream network.Stream) {
 	pid := stream.Conn().RemotePeer().Pretty()
+	if !t.acceptStream(stream) {
+		return
+	}
 	logger.Infof("Connected to: %s(%s)", pid, stream.Protocol())
 
-	// TODO: figure out why sometimes the localaddr is 0.0.0.0
+	// TODO: figure out why sometimes the localaddr is 0.0.0.0 (or ::)
 	localAddr := stream.Conn().LocalMultiaddr().String()
 	logger.Infof("local addr in message: %s", localAddr)
-	localAddr = strings.Replace(localAddr, "0.0.0.0", "127.0.0.1", 1)
-	if msg, err := common.NewBootstrapMessage(
-		t.bootstrapper.ChannelId,
-		t.bootstrapper.ChannelPassword,
-		localAddr,
-		common.PeerParam{
-			ChannelId: common.TssCfg.ChannelId,
-			Moniker:   common.TssCfg.Moniker,
-			Msg:       common.TssCfg.Message,
-			Id:        string(common.TssCfg.Id),
-			N:         common.TssCfg.Parties,
-			T:         common.TssCfg.Threshold,
-			NewN:      common.TssCfg.NewParties,
-			NewT:      common.TssCfg.NewThreshold,
-			IsOld:     common.TssCfg.IsOldCommittee,
-			IsNew:     !common.TssCfg.IsOldCommittee,
-		}); err == nil {
-		payload, err := proto.Marshal(msg)
-		if err != nil {
-			common.Panic(fmt.Errorf("bootstrap message cannot be marshaled to protobuf payload: %v", err))
-		}
-		messageLength := int32(len(payload))
-		binary.Write(stream, binary.BigEndian, &messageLength)
-		stream.Write(payload)
-	} else {
-		logger.Errorf("failed to encrypt bootstrap message: %v", err)
-	}
-
-	var messageLength int32
-	// deliberately not handle err here
-	// possible err maybe:
-	// EOF - on receiving ssdp live message, peer will close conn directly
-	// Read timeout - same with above. If we reading before peer close conn, we will timeout
-	binary.Read(stream, binary.BigEndian, &messageLength)
-	payload := make([]byte, messageLength)
-	stream.Read(payload)
-	var peerMsg common.BootstrapMessage
-	proto.Unmarshal(payload, &peerMsg)
-	if err := t.bootstrapper.HandleBootstrapMsg(peerMsg); err != nil {
-		// peer's channel id or channel password is not correct, we can wait them fix
+	localAddr = common.LoopbackIfUnspecified(localAddr)
+	if err := t.bootstrapper.Handshake(stream, localAddr, pid); err != nil {
+		// EOF - on receiving ssdp live message, peer will close conn directly
+		// otherwise peer's channel id or channel password is not correct, we can wait them fix
 		logger.Error(err)
 		return
 	}
 }
 
-func (t *p2pTransporter) readDataRoutine(pid string, stream network.Stream) {
-	var messageLength int32
+func (t *p2pTransporter) readDataRoutine(session *peerSession) {
+	stream, _ := session.current()
 	for {
-		err := binary.Read(stream.(network.Stream), binary.BigEndian, &messageLength)
-		if err != nil {
-			if yamuxErr, ok := err.(*yamux.YamuxError); ok {
-				if yamuxErr.Error() == yamux.ErrConnectionReset.Error() {
-					break
-				} else {
-					common.Panic(fmt.Errorf("failed to read message bytes length: %v, from: %s", err, pid))
-				}
-			} else {
-				common.Panic(fmt.Errorf("failed to read message bytes length: %v, from: %s", err, pid))
-			}
+		err := t.codec.ReadFrameFunc(stream, func(frameType byte, payload []byte) error {
+			return t.handleFrame(session, frameType, payload)
+		})
+		if e, ok := err.(*common.MalformedFrameError); ok {
+			// reconnection doesn't help, peer would resend the same, we stop reading from it and let others go on
+			stream.Reset()
+			err = fmt.Errorf("received a malformed message from %s: %v", session.pid, e)
+			session.lose(err)
+			t.reportMisbehavior(session.pid, err)
+			return
 		}
-
-		payloadWithTypePrefix := make([]byte, 0, messageLength)
-		readBytes := 0
-		logger.Debugf("going to received a message with length: %d", messageLength)
-		for readBytes < int(messageLength) {
-			bufSize := 4096
-			if int(messageLength)-readBytes < bufSize {
-				bufSize = int(messageLength) - readBytes
-			}
-			buf := make([]byte, bufSize)
-			readLength, err := stream.(network.Stream).Read(buf)
-			// this line sucks
-			time.Sleep(time.Millisecond)
-			if err != nil {
-				common.Panic(fmt.Errorf("failed to read protobuf message: %v, from: %s", err, pid))
-			} else {
-				payloadWithTypePrefix = append(payloadWithTypePrefix, buf...)
-			}
-			readBytes += readLength
-		}
-		logger.Debugf("received a message with length: %d", readBytes)
-		if readBytes != int(messageLength) {
-			common.Panic(fmt.Errorf("failed to read protobuf message: length: %d doesn't match prefix: %d, from: %s", readBytes, int(messageLength), pid))
-		}
-		payload := payloadWithTypePrefix[1:messageLength]
-		switch payloadWithTypePrefix[0] {
-		case MessagePrefix:
-			var m tss.MessageWrapper
-			logger.Debugf("received a tss message from: %s", m.From.GetMoniker())
-			err := proto.Unmarshal(payload, &m)
-			if err != nil {
-				common.Panic(fmt.Errorf("failed to unmarshal MessagePrefix, not a valid protobuf format: %v. from: %s", err, pid))
+		if err != nil {
+			if session.isFinished() {
+				logger.Debugf("peer %s has finished", session.pid)
+				return
 			}
-			if t.broadcastSanityCheck && m.IsBroadcast {
-				// we cannot use gob encoding here because the type spec registered relies on message sequence
-				// in other word, it might be not deterministic https://stackoverflow.com/a/33228913/1147187
-				hash := sha256.Sum256(payload)
-
-				var to []string
-				for _, id := range m.To {
-					to = append(to, id.Id)
-				}
-
-				msgWithHash := &P2PMessageWithHash{
-					From:                    pid,
-					To:                      to,
-					Hash:                    hash[:],
-					OriginMsg:               payload,
-					IsToOldAndNewCommittees: m.IsToOldAndNewCommittees}
-				t.sanityCheckMtx.Lock()
-				t.pendingCheckHashMsg[keyOf(msgWithHash)] = msgWithHash
-				var numOfDest int
-				if to == nil {
-					for _, p := range t.expectedPeers {
-						if p.Pretty() != pid {
-							// send our hashing of this message
-							msgWithHashPayload, err := proto.Marshal(msgWithHash)
-							if err != nil {
-								common.Panic(fmt.Errorf("cannot marshal P2PMessageWithHash: %v", err))
-							}
-							msgWithHashPayload = append([]byte{HashMessagePrefix}, msgWithHashPayload...)
-							err = t.Send(msgWithHashPayload, common.TssClientId(p.Pretty()))
-							numOfDest++
-							if err != nil {
-								common.Panic(fmt.Errorf("cannot send P2PMessageWithHash: %v", err))
-							}
-						}
-					}
-				} else {
-					for _, p := range to {
-						if p != pid && p != common.TssCfg.Id.String() {
-							msgWithHashPayload, err := proto.Marshal(msgWithHash)
-							if err != nil {
-								common.Panic(fmt.Errorf("cannot marshal P2PMessageWithHash"))
-							}
-							msgWithHashPayload = append([]byte{HashMessagePrefix}, msgWithHashPayload...)
-							err = t.Send(msgWithHashPayload, common.TssClientId(p))
-							numOfDest++
-							if err != nil {
-								common.Panic(fmt.Errorf("cannot send P2PMessageWithHash: %v", err))
-							}
-						}
-					}
-				}
-				if t.verifiedPeersBroadcastMsgGuarded(keyOf(msgWithHash), numOfDest) {
-					t.receiveCh <- common.P2pMessageWrapper{MessageWrapperBytes: payload}
-					delete(t.pendingCheckHashMsg, keyOf(msgWithHash))
-				}
-				t.sanityCheckMtx.Unlock()
-			} else {
-				t.receiveCh <- common.P2pMessageWrapper{MessageWrapperBytes: payload}
+			if t.isClosed() {
+				// peer has closed its side (or reset) after we said bye
+				stream.Reset()
+				return
 			}
-		case HashMessagePrefix:
-			var m P2PMessageWithHash
-			logger.Debugf("received a hash message: %s from: %s", hex.EncodeToString(m.Hash), m.GetFrom())
-			err := proto.Unmarshal(payload, &m)
-			if err != nil {
-				common.Panic(fmt.Errorf("failed to unmarshal MessagePrefix, not a valid protobuf format: %v. from: %s", err, pid))
+			if stream = t.waitForReconnection(session, stream, err); stream == nil {
+				return
 			}
+		}
+	}
+}
 
-			if t.broadcastSanityCheck {
-				key := keyOf(&m)
-				t.sanityCheckMtx.Lock()
-				t.receivedPeersHashMsg[key] = append(t.receivedPeersHashMsg[key], &m)
-				var numOfDest int
-				if m.To == nil {
-					numOfDest = len(t.expectedPeers) - 1 // exclude the sender
-				} else {
-					if m.IsToOldAndNewCommittees {
-						numOfDest = len(m.To) - 2 // exclude ourself and sender for resharing ack
-					} else {
-						numOfDest = len(m.To) - 1 // exclude ourself
-					}
-				}
-				if t.verifiedPeersBroadcastMsgGuarded(key, numOfDest) {
-					t.receiveCh <- common.P2pMessageWrapper{MessageWrapperBytes: t.pendingCheckHashMsg[key].OriginMsg}
-					delete(t.pendingCheckHashMsg, key)
-				}
-				t.sanityCheckMtx.Unlock()
-			} else {
-				logger.Errorf("peer %s configuration is not consistent - sanity check is enabled", pid)
-			}
+// handleFrame handles a frame read from party stream with peer, an error means the stream is broken (i.e. a frame is truncated),
+// messages not acknowledged yet are resent after reconnection. payload is in the pooled buffer of the stream reader,
+// what outlives the call is copied (see handleMessage)
+func (t *p2pTransporter) handleFrame(session *peerSession, frameType byte, payload []byte) error {
+	switch frameType {
+	case SequencedMessagePrefix:
+		seq, err := parseSequence(payload)
+		if err != nil {
+			return fmt.Errorf("failed to read sequenced message: %v, from: %s", err, session.pid)
+		}
+		if session.received(seq) {
+			t.handleMessage(session.pid, payload[sequenceLength:])
+		}
+	case AckMessagePrefix:
+		seq, err := parseSequence(payload)
+		if err != nil {
+			return fmt.Errorf("failed to read acknowledgement: %v, from: %s", err, session.pid)
 		}
+		session.acknowledged(seq)
+		t.reportDelivered(session.pid, seq)
+	case ByeMessagePrefix:
+		session.finish()
+	default:
+		t.handleMessage(session.pid, append([]byte{frameType}, payload...))
 	}
+	return nil
 }
 
-// guarded by t.sanityCheckMtx
-func (t *p2pTransporter) verifiedPeersBroadcastMsgGuarded(key p2pMessageKey, numOfDest int) bool {
-	if t.pendingCheckHashMsg[key] == nil {
-		logger.Debugf("didn't receive the main message: %s yet", key)
-		return false
-	} else if len(t.receivedPeersHashMsg[key]) != numOfDest {
-		logger.Debugf("didn't receive enough peer's hash messages: %s yet. Expected: %d, Got: %d", key, numOfDest, len(t.receivedPeersHashMsg[key]))
-		return false
-	} else {
-		for _, hashMsg := range t.receivedPeersHashMsg[key] {
-			if string(hashMsg.Hash) != string(t.pendingCheckHashMsg[key].Hash) {
-				common.Panic(fmt.Errorf("someone in network is malicious")) // TODO: better logging, i.e. log which one is malicious in what way
-			}
+func (t *p2pTransporter) handleMessage(pid string, payloadWithTypePrefix []byte) {
+	if len(payloadWithTypePrefix) == 0 {
+		t.reportMisbehavior(pid, fmt.Errorf("received an empty message from: %s", pid))
+		return
+	}
+	payload := payloadWithTypePrefix[1:]
+	switch payloadWithTypePrefix[0] {
+	case MessagePrefix:
+		// tss message is handed over as it is, others are copied by unmarshaling
+		t.handleTssMessage(pid, append([]byte(nil), payload...), nil)
+	case SignedMessagePrefix:
+		var m SignedBroadcast
+		err := proto.Unmarshal(payload, &m)
+		if err != nil {
+			t.reportMisbehavior(pid, fmt.Errorf("failed to unmarshal SignedMessagePrefix, not a valid protobuf format: %v. from: %s", err, pid))
+			return
+		}
+		key, err := broadcastKey(pid, m.Msg)
+		if err != nil {
+			t.reportMisbehavior(pid, fmt.Errorf("dropped a broadcast from %s: %v", pid, err))
+			return
+		}
+		hash := sha256.Sum256(m.Msg)
+		if err := verifySignature(pid, broadcastSigningBytes(key, hash[:]), m.Signature); err != nil {
+			t.reportMisbehavior(pid, fmt.Errorf("dropped a broadcast not signed by %s: %v", pid, err))
+			return
 		}
+		t.handleTssMessage(pid, m.Msg, m.Signature)
+	case HashMessagePrefix:
+		var m BroadcastDigest
+		err := proto.Unmarshal(payload, &m)
+		if err != nil {
+			t.reportMisbehavior(pid, fmt.Errorf("failed to unmarshal HashMessagePrefix, not a valid protobuf format: %v. from: %s", err, pid))
+			return
+		}
+		logger.Debugf("received a digest of round %s from: %s", m.Round, pid)
 
-		delete(t.receivedPeersHashMsg, key)
-		logger.Debugf("received enough peer's hash messages: %s. Expected: %d, Got: %d", key, numOfDest, len(t.receivedPeersHashMsg[key]))
-		return true
+		if !t.broadcastSanityCheck {
+			logger.Errorf("peer %s configuration is not consistent - sanity check is enabled", pid)
+			return
+		}
+		t.handleDigest(pid, &m)
+	case EchoMessagePrefix, ReadyMessagePrefix:
+		var m ReliableBroadcastMessage
+		if err := proto.Unmarshal(payload, &m); err != nil {
+			t.reportMisbehavior(pid, fmt.Errorf("failed to unmarshal ReliableBroadcastMessage, not a valid protobuf format: %v. from: %s", err, pid))
+			return
+		}
+		if !t.reliableBroadcast {
+			logger.Errorf("peer %s configuration is not consistent - reliable broadcast is enabled", pid)
+			return
+		}
+		if payloadWithTypePrefix[0] == EchoMessagePrefix {
+			t.handleEcho(pid, &m)
+		} else {
+			t.handleReady(pid, &m)
+		}
+	case BlameMessagePrefix:
+		var b Blame
+		if err := proto.Unmarshal(payload, &b); err != nil {
+			t.reportMisbehavior(pid, fmt.Errorf("failed to unmarshal BlameMessagePrefix, not a valid protobuf format: %v. from: %s", err, pid))
+			return
+		}
+		t.handleBlame(pid, &b)
 	}
 }
 
-func (t *p2pTransporter) initBootstrapConnection(dht *libp2pdht.IpfsDHT) {
+// handleTssMessage delivers a tss message from pid, broadcasts are delivered after peers confirm they received the same if sanity check is enabled,
+// or by reliable broadcast if it is enabled.
+// senderSignature is sender's signature of the message hash, nil if the message is not a broadcast
+func (t *p2pTransporter) handleTssMessage(pid string, payload, senderSignature []byte) {
+	var m tss.MessageWrapper
+	err := proto.Unmarshal(payload, &m)
+	if err != nil {
+		t.reportMisbehavior(pid, fmt.Errorf("failed to unmarshal MessagePrefix, not a valid protobuf format: %v. from: %s", err, pid))
+		return
+	}
+	// stream is authenticated by peer's node key (party id), a message claiming another sender is forged
+	if m.From.GetId() != pid {
+		t.reportMisbehavior(pid, fmt.Errorf("dropped a forged message from %s, claimed sender: %s(%s)", pid, m.From.GetMoniker(), m.From.GetId()))
+		return
+	}
+	logger.Debugf("received a tss message from: %s", m.From.GetMoniker())
+	if t.reliableBroadcast && m.IsBroadcast {
+		if senderSignature == nil {
+			t.reportMisbehavior(pid, fmt.Errorf("broadcast from %s is not signed, it might be running an older version", pid))
+			return
+		}
+		t.handleInitialBroadcast(pid, payload, senderSignature)
+		return
+	}
+	if !t.broadcastSanityCheck || !m.IsBroadcast {
+		t.receiveCh <- common.P2pMessageWrapper{MessageWrapperBytes: payload}
+		return
+	}
+	if senderSignature == nil {
+		t.reportMisbehavior(pid, fmt.Errorf("broadcast from %s is not signed, it might be running an older version", pid))
+		return
+	}
+	t.handleCheckedBroadcast(pid, payload, senderSignature, &m)
+}
+
+func (t *p2pTransporter) initBootstrapConnection(dht *libp2pdht.IpfsDHT) error {
 	logger.Debugf("initialize bootstrap connection")
 	for _, pid := range t.expectedPeers {
 		// we only connect parties whose id greater than us
 		if strings.Compare(t.host.ID().String(), pid.String()) >= 0 {
 			continue
 		}
-		go t.connectRoutine(dht, pid, bootstrapProtocolId)
+		go t.connectRoutine(dht, pid, t.bootstrapProtocol)
 	}
 
-	for {
-		if t.bootstrapper.IsFinished() {
-			break
-		} else {
-			time.Sleep(time.Second)
-		}
-	}
+	return t.bootstrapper.WaitForPeers()
 }
 
-func (t *p2pTransporter) initConnection(dht *libp2pdht.IpfsDHT) {
+// initConnection connects expected peers, checks they agree on the committee and sets up sessions with them.
+// Peers should be connected within t.connectTimeout (forever if it is 0)
+func (t *p2pTransporter) initConnection(dht *libp2pdht.IpfsDHT) error {
 	for _, pid := range t.expectedPeers {
 		if stream, ok := t.streams.Load(pid.Pretty()); ok && stream != nil {
 			continue
@@ -534,19 +642,53 @@ func (t *p2pTransporter) initConnection(dht *libp2pdht.IpfsDHT) {
 		if strings.Compare(t.host.ID().String(), pid.String()) >= 0 {
 			continue
 		}
-		go t.connectRoutine(dht, pid, partyProtocolId)
+		go t.connectRoutine(dht, pid, t.partyProtocol)
 	}
 
+	var deadline <-chan time.Time
+	if t.connectTimeout > 0 {
+		timer := time.NewTimer(t.connectTimeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
 	for atomic.LoadInt32(&t.numOfStreams) < int32(len(t.expectedPeers)) {
-		time.Sleep(10 * time.Millisecond)
+		select {
+		case <-deadline:
+			return t.connectTimeoutError()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if t.agreement != nil {
+		if err := t.agree(); err != nil {
+			// peers disagreeing with us should get our agreement before streams are reset
+			time.Sleep(agreementLinger)
+			return err
+		}
 	}
 	t.streams.Range(func(pid, stream interface{}) bool {
-		go t.readDataRoutine(pid.(string), stream.(network.Stream))
+		// the same rule with connecting: party with smaller id redials when the stream is dropped
+		dialer := strings.Compare(t.host.ID().String(), pid.(string)) < 0
+		session := newPeerSession(pid.(string), stream.(network.Stream), dialer, t.codec)
+		t.sessions.Store(pid, session)
+		go t.readDataRoutine(session)
+		go session.writeRoutine(t.closed)
 		return true
 	})
+	return nil
+}
+
+// connectTimeoutError tells which peers are not connected within t.connectTimeout
+func (t *p2pTransporter) connectTimeoutError() error {
+	var missing []string
+	for _, pid := range t.expectedPeers {
+		if _, ok := t.streams.Load(pid.Pretty()); !ok {
+			missing = append(missing, fmt.Sprintf("%s(%s)", t.monikerOf(pid.Pretty()), pid.Pretty()))
+		}
+	}
+	return fmt.Errorf("%d of %d peer(s) are not connected within %v: %s", len(missing), len(t.expectedPeers), t.connectTimeout, strings.Join(missing, ", "))
 }
 
-func (t *p2pTransporter) connectRoutine(dht *libp2pdht.IpfsDHT, pid peer.ID, protocolId string) {
+func (t *p2pTransporter) connectRoutine(dht *libp2pdht.IpfsDHT, pid peer.ID, protocolId protocol.ID) {
 	logger.Debugf("trying to connect with %s", pid.Pretty())
 	timeout := time.NewTimer(15 * time.Minute)
 	defer func() {
@@ -556,9 +698,9 @@ func (t *p2pTransporter) connectRoutine(dht *libp2pdht.IpfsDHT, pid peer.ID, pro
 	for {
 		select {
 		case <-t.closed:
-			break
+			return
 		case <-timeout.C:
-			break
+			return
 		default:
 			time.Sleep(1000 * time.Millisecond)
 			if len(t.host.Peerstore().Addrs(pid)) == 0 {
@@ -575,7 +717,7 @@ func (t *p2pTransporter) connectRoutine(dht *libp2pdht.IpfsDHT, pid peer.ID, pro
 					return
 				}
 				logger.Debug("Connecting to:", pid)
-				stream, err := t.host.NewStream(t.ctx, pid, protocol.ID(protocolId))
+				stream, err := t.host.NewStream(t.ctx, pid, protocolId)
 
 				if err != nil {
 					logger.Info("Normal Connection failed:", err)
@@ -585,12 +727,7 @@ func (t *p2pTransporter) connectRoutine(dht *libp2pdht.IpfsDHT, pid peer.ID, pro
 						return
 					}
 				} else {
-					switch protocolId {
-					case partyProtocolId:
-						t.handleStream(stream)
-					case bootstrapProtocolId:
-						t.handleSigner(stream)
-					}
+					t.handleProtocolStream(protocolId, stream)
 					return
 				}
 			} else {
@@ -606,17 +743,13 @@ func (t *p2pTransporter) connectRoutine(dht *libp2pdht.IpfsDHT, pid peer.ID, pro
 						return
 					}
 
-					stream, err := t.host.NewStream(t.ctx, pid, protocol.ID(protocolId))
+					// i.e. session of peer is not running yet on its host, it is retried like a failed connection
+					stream, err := t.host.NewStream(t.ctx, pid, protocolId)
 					if err != nil {
-						logger.Info("Direct Connection failed, Will give up")
-						common.Panic(err)
+						logger.Debugf("Direct stream to %s failed, will retry, err: %v", pid.Pretty(), err)
+						continue
 					} else {
-						switch protocolId {
-						case partyProtocolId:
-							t.handleStream(stream)
-						case bootstrapProtocolId:
-							t.handleSigner(stream)
-						}
+						t.handleProtocolStream(protocolId, stream)
 						return
 					}
 				}
@@ -625,7 +758,7 @@ func (t *p2pTransporter) connectRoutine(dht *libp2pdht.IpfsDHT, pid peer.ID, pro
 	}
 }
 
-func (t *p2pTransporter) tryRelaying(pid peer.ID, protocolId string) error {
+func (t *p2pTransporter) tryRelaying(pid peer.ID, protocolId protocol.ID) error {
 	t.host.Network().(*swarm.Swarm).Backoff().Clear(pid)
 	relayaddr, err := multiaddr.NewMultiaddr("/p2p-circuit/p2p/" + pid.Pretty())
 	relayInfo := peer.AddrInfo{
@@ -637,68 +770,16 @@ func (t *p2pTransporter) tryRelaying(pid peer.ID, protocolId string) error {
 		logger.Warning("Relay Connection failed:", err)
 		return err
 	}
-	stream, err := t.host.NewStream(t.ctx, pid, protocol.ID(protocolId))
+	stream, err := t.host.NewStream(t.ctx, pid, protocolId)
 	if err != nil {
 		logger.Warning("Relay Stream failed:", err)
 		return err
 	}
-	switch protocolId {
-	case partyProtocolId:
-		t.handleStream(stream)
-	case bootstrapProtocolId:
-		t.handleSigner(stream)
-	}
+	t.handleProtocolStream(protocolId, stream)
 	return nil
 }
 
-func (t *p2pTransporter) setupDHTClient() *libp2pdht.IpfsDHT {
-	//ds, err := leveldb.NewDatastore(t.pathToRouteTable, nil)
-	//if err != nil {
-	//	common.Panic(err)
-	//}
-	ds := datastore.NewMapDatastore()
-
-	kademliaDHT, err := libp2pdht.New(
-		t.ctx,
-		t.host,
-		opts.Datastore(ds),
-		opts.Client(true),
-	)
-	if err != nil {
-		common.Panic(err)
-	}
-
-	// Connect to bootstrap peers
-	for _, bootstrapAddr := range t.bootstrapPeers {
-		bootstrapPeerInfo, err := peer.AddrInfoFromP2pAddr(bootstrapAddr)
-		if err != nil {
-			common.Panic(err)
-		}
-		if err := t.host.Connect(t.ctx, *bootstrapPeerInfo); err != nil {
-			logger.Warning(err)
-		} else {
-			logger.Info("Connection established with bootstrap node:", *bootstrapPeerInfo)
-		}
-	}
-
-	// Connect to relay peers to get NAT support
-	// TODO: exclude relay peers that are same with bootstrap peers
-	for _, relayAddr := range t.relayPeers {
-		relayPeerInfo, err := peer.AddrInfoFromP2pAddr(relayAddr)
-		if err != nil {
-			common.Panic(err)
-		}
-		if err := t.host.Connect(t.ctx, *relayPeerInfo); err != nil {
-			logger.Warning(err)
-		} else {
-			logger.Info("Connection established with relay node:", *relayPeerInfo)
-		}
-	}
-
-	return kademliaDHT
-}
-
-func (t *p2pTransporter) setExpectedPeers(nodeId string, signers map[string]int, ps peerstore.Peerstore, config *common.P2PConfig) {
+func (t *p2pTransporter) setExpectedPeers(nodeId string, signers map[string]int, ps peerstore.Peerstore, config *common.P2PConfig) error {
 	mergedExpectedPeers := make(map[string]string) // peer -> addr
 	for idx, expectedPeer := range config.ExpectedPeers {
 		moniker := GetMonikerFromExpectedPeers(expectedPeer)
@@ -720,7 +801,7 @@ func (t *p2pTransporter) setExpectedPeers(nodeId string, signers map[string]int,
 
 	for expectedPeer, peerAddr := range mergedExpectedPeers {
 		if pid, err := peer.IDB58Decode(string(GetClientIdFromExpectedPeers(expectedPeer))); err != nil {
-			common.Panic(err)
+			return fmt.Errorf("invalid id of expected peer %s: %v", expectedPeer, err)
 		} else {
 			if pid.Pretty() == nodeId {
 				continue
@@ -738,4 +819,5 @@ func (t *p2pTransporter) setExpectedPeers(nodeId string, signers map[string]int,
 			t.expectedPeers = append(t.expectedPeers, pid)
 		}
 	}
+	return nil
 }
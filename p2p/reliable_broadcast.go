@@ -0,0 +1,245 @@
+package p2p
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/bnb-chain/tss-lib/v2/tss"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bnb-chain/tss/common"
+)
+
+// reliableBroadcast is the state of one broadcast in Bracha's reliable broadcast.
+// A party echoes the broadcast it receives from the sender, gets ready once enough recipients echo the same
+// (or f+1 recipients are ready, in case it missed the echoes) and delivers once 2f+1 recipients are ready.
+// So that honest parties either deliver the same broadcast or none of them delivers, even if the sender equivocates or stops halfway
+type reliableBroadcast struct {
+	recipients map[string]bool   // parties expected to deliver, known once we have a message signed by the sender
+	messages   map[string][]byte // hash -> origin message, from sender or echoes
+	echoes     map[string]string // recipient -> hash it echoed
+	readies    map[string]string // recipient -> hash it is ready for
+	echoed     bool
+	ready      bool
+	delivered  bool
+}
+
+// broadcastOutcome is what to do once t.reliableBroadcastMtx is released, so that a slow peer or a slow consumer of receiveCh
+// doesn't hold up other broadcasts
+type broadcastOutcome struct {
+	sends   []broadcastSend // echoes and readies to send, in order
+	payload []byte          // broadcast to deliver, nil if it is not delivered yet
+}
+
+type broadcastSend struct {
+	name       string // echo or ready, for logs
+	payload    []byte
+	recipients []string
+}
+
+func newReliableBroadcast() *reliableBroadcast {
+	return &reliableBroadcast{
+		messages: make(map[string][]byte),
+		echoes:   make(map[string]string),
+		readies:  make(map[string]string),
+	}
+}
+
+// count returns how many recipients voted for each hash
+func (rb *reliableBroadcast) count(votes map[string]string) map[string]int {
+	counts := make(map[string]int)
+	for party, hash := range votes {
+		if rb.recipients[party] {
+			counts[hash]++
+		}
+	}
+	return counts
+}
+
+// handleInitialBroadcast starts reliable broadcast of a message we receive from its sender
+func (t *p2pTransporter) handleInitialBroadcast(sender string, payload, senderSignature []byte) {
+	msg := &ReliableBroadcastMessage{SenderSignature: senderSignature, OriginMsg: payload}
+	key, m, err := t.verifyEcho(msg)
+	if err != nil {
+		logger.Errorf("dropped a broadcast from %s: %v", sender, err)
+		return
+	}
+
+	t.reliableBroadcastMtx.Lock()
+	outcome := t.echoGuarded(sender, key, m, msg)
+	t.reliableBroadcastMtx.Unlock()
+	t.applyBroadcastOutcome(outcome)
+}
+
+// guarded by t.reliableBroadcastMtx
+func (t *p2pTransporter) echoGuarded(sender string, key p2pMessageKey, m *tss.MessageWrapper, msg *ReliableBroadcastMessage) broadcastOutcome {
+	var outcome broadcastOutcome
+	rb := t.reliableBroadcastGuarded(key)
+	if rb.echoed {
+		logger.Warningf("ignored another version of broadcast %s from %s", key, sender)
+		return outcome
+	}
+	rb.echoed = true
+	t.addBroadcastMessageGuarded(rb, sender, m, msg)
+	rb.echoes[t.host.ID().Pretty()] = string(msg.Hash)
+	t.sendToRecipientsGuarded(&outcome, rb, EchoMessagePrefix, msg)
+	t.progressGuarded(&outcome, key, rb)
+	return outcome
+}
+
+// handleEcho records an echo (with the message echoed) from pid
+func (t *p2pTransporter) handleEcho(pid string, msg *ReliableBroadcastMessage) {
+	key, m, err := t.verifyEcho(msg)
+	if err != nil {
+		logger.Errorf("dropped an echo from %s: %v", pid, err)
+		return
+	}
+
+	t.reliableBroadcastMtx.Lock()
+	outcome := t.receiveEchoGuarded(pid, key, m, msg)
+	t.reliableBroadcastMtx.Unlock()
+	t.applyBroadcastOutcome(outcome)
+}
+
+// guarded by t.reliableBroadcastMtx
+func (t *p2pTransporter) receiveEchoGuarded(pid string, key p2pMessageKey, m *tss.MessageWrapper, msg *ReliableBroadcastMessage) broadcastOutcome {
+	var outcome broadcastOutcome
+	rb := t.reliableBroadcastGuarded(key)
+	t.addBroadcastMessageGuarded(rb, m.From.GetId(), m, msg)
+	if _, ok := rb.echoes[pid]; !ok {
+		rb.echoes[pid] = string(msg.Hash)
+	}
+	t.progressGuarded(&outcome, key, rb)
+	return outcome
+}
+
+// handleReady records pid is ready to deliver the broadcast
+func (t *p2pTransporter) handleReady(pid string, msg *ReliableBroadcastMessage) {
+	t.reliableBroadcastMtx.Lock()
+	outcome := t.receiveReadyGuarded(pid, msg)
+	t.reliableBroadcastMtx.Unlock()
+	t.applyBroadcastOutcome(outcome)
+}
+
+// guarded by t.reliableBroadcastMtx
+func (t *p2pTransporter) receiveReadyGuarded(pid string, msg *ReliableBroadcastMessage) broadcastOutcome {
+	var outcome broadcastOutcome
+	key := p2pMessageKey(msg.Key)
+	rb := t.reliableBroadcastGuarded(key)
+	if _, ok := rb.readies[pid]; !ok {
+		rb.readies[pid] = string(msg.Hash)
+	}
+	t.progressGuarded(&outcome, key, rb)
+	return outcome
+}
+
+// verifyEcho checks the echoed message is a broadcast signed by its sender, and fills in its key and hash
+func (t *p2pTransporter) verifyEcho(msg *ReliableBroadcastMessage) (p2pMessageKey, *tss.MessageWrapper, error) {
+	var m tss.MessageWrapper
+	if err := proto.Unmarshal(msg.OriginMsg, &m); err != nil {
+		return "", nil, fmt.Errorf("not a valid tss message: %v", err)
+	}
+	key, err := broadcastKey(m.From.GetId(), msg.OriginMsg)
+	if err != nil {
+		return "", nil, err
+	}
+	hash := sha256.Sum256(msg.OriginMsg)
+	if err := verifySignature(m.From.GetId(), broadcastSigningBytes(key, hash[:]), msg.SenderSignature); err != nil {
+		return "", nil, fmt.Errorf("not signed by %s: %v", m.From.GetId(), err)
+	}
+	msg.Key = string(key)
+	msg.Hash = hash[:]
+	return key, &m, nil
+}
+
+// guarded by t.reliableBroadcastMtx
+func (t *p2pTransporter) reliableBroadcastGuarded(key p2pMessageKey) *reliableBroadcast {
+	rb, ok := t.reliableBroadcasts[key]
+	if !ok {
+		rb = newReliableBroadcast()
+		t.reliableBroadcasts[key] = rb
+	}
+	return rb
+}
+
+// addBroadcastMessageGuarded keeps the message so that we can deliver it even if the sender didn't send it to us, guarded by t.reliableBroadcastMtx
+func (t *p2pTransporter) addBroadcastMessageGuarded(rb *reliableBroadcast, sender string, m *tss.MessageWrapper, msg *ReliableBroadcastMessage) {
+	rb.messages[string(msg.Hash)] = msg.OriginMsg
+	if rb.recipients != nil {
+		return
+	}
+	rb.recipients = t.recipientsOf(m)
+	rb.recipients[t.host.ID().Pretty()] = true
+	delete(rb.recipients, sender)
+}
+
+// progressGuarded gets ready and delivers the broadcast once there are enough votes, what to send and deliver is added to outcome.
+// guarded by t.reliableBroadcastMtx
+func (t *p2pTransporter) progressGuarded(outcome *broadcastOutcome, key p2pMessageKey, rb *reliableBroadcast) {
+	if rb.recipients == nil || rb.delivered {
+		return
+	}
+	n := len(rb.recipients)
+	f := (n - 1) / 3 // number of malicious recipients tolerated
+	if !rb.ready {
+		readyFor := ""
+		for hash, count := range rb.count(rb.echoes) {
+			if count >= (n+f+2)/2 { // ceil((n+f+1)/2), two such quorums always share an honest party
+				readyFor = hash
+			}
+		}
+		for hash, count := range rb.count(rb.readies) {
+			if count >= f+1 { // at least one honest party is ready
+				readyFor = hash
+			}
+		}
+		if readyFor != "" {
+			rb.ready = true
+			rb.readies[t.host.ID().Pretty()] = readyFor
+			t.sendToRecipientsGuarded(outcome, rb, ReadyMessagePrefix, &ReliableBroadcastMessage{Key: string(key), Hash: []byte(readyFor)})
+		}
+	}
+	for hash, count := range rb.count(rb.readies) {
+		if payload, ok := rb.messages[hash]; ok && count >= 2*f+1 {
+			logger.Debugf("delivered broadcast %s, ready: %d/%d", key, count, n)
+			rb.delivered = true
+			outcome.payload = payload
+			return
+		}
+	}
+}
+
+// sendToRecipientsGuarded adds msg to outcome, to be sent to other recipients once the lock is released
+// guarded by t.reliableBroadcastMtx
+func (t *p2pTransporter) sendToRecipientsGuarded(outcome *broadcastOutcome, rb *reliableBroadcast, prefix byte, msg *ReliableBroadcastMessage) {
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		common.Panic(fmt.Errorf("cannot marshal ReliableBroadcastMessage: %v", err))
+	}
+	send := broadcastSend{name: "echo", payload: append([]byte{prefix}, payload...)}
+	if prefix == ReadyMessagePrefix {
+		send.name = "ready"
+	}
+	self := t.host.ID().Pretty()
+	for recipient := range rb.recipients {
+		if recipient != self {
+			send.recipients = append(send.recipients, recipient)
+		}
+	}
+	outcome.sends = append(outcome.sends, send)
+}
+
+// applyBroadcastOutcome sends echoes and readies then delivers the broadcast if it is, it must not hold t.reliableBroadcastMtx
+func (t *p2pTransporter) applyBroadcastOutcome(outcome broadcastOutcome) {
+	for _, send := range outcome.sends {
+		for _, recipient := range send.recipients {
+			if err := t.Send(send.payload, common.TssClientId(recipient)); err != nil {
+				t.reportError(recipient, fmt.Errorf("cannot send %s to %s: %v", send.name, recipient, err))
+				return
+			}
+		}
+	}
+	if outcome.payload != nil {
+		t.receiveCh <- common.P2pMessageWrapper{MessageWrapperBytes: outcome.payload}
+	}
+}
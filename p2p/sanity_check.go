@@ -0,0 +1,281 @@
+package p2p
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/bnb-chain/tss-lib/v2/tss"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bnb-chain/tss/common"
+)
+
+// broadcastRound collects broadcasts of one message type for broadcast sanity check.
+// Once we receive the broadcast from every sender of the round, we send one digest of all of them to other recipients,
+// broadcasts of the round are delivered when digests of all other recipients agree with ours. A round is dropped once delivered
+type broadcastRound struct {
+	numOfSenders int                                       // how many parties (except us) broadcast in this round
+	recipients   map[string]bool                           // parties (except us) who receive broadcasts of this round, known once we receive all of them
+	received     map[string]*P2PMessageWithHash            // sender -> what we received, signed by us
+	payloads     map[string][]byte                         // sender -> broadcast to deliver
+	digests      map[string]map[string]*P2PMessageWithHash // recipient -> sender -> what the recipient received
+	digestSent   bool
+}
+
+// roundOutcome is what to do with a round once t.sanityCheckMtx is released, so that a slow consumer of receiveCh
+// or ControlCh, or a slow peer, doesn't hold up other rounds
+type roundOutcome struct {
+	digest     []byte   // our digest of the round to send, once we receive all broadcasts of it
+	recipients []string // who our digest is sent to
+	payloads   [][]byte // broadcasts verified by all recipients
+	culprit    string   // peer whose digest doesn't cover the same senders with ours
+	err        error
+}
+
+func newBroadcastRound() *broadcastRound {
+	return &broadcastRound{
+		received: make(map[string]*P2PMessageWithHash),
+		payloads: make(map[string][]byte),
+		digests:  make(map[string]map[string]*P2PMessageWithHash),
+	}
+}
+
+// handleCheckedBroadcast keeps a broadcast from sender until peers confirm they received the same
+func (t *p2pTransporter) handleCheckedBroadcast(sender string, payload, senderSignature []byte, m *tss.MessageWrapper) {
+	key, err := broadcastKey(sender, payload)
+	if err != nil {
+		t.reportMisbehavior(sender, fmt.Errorf("cannot identify broadcast from %s: %v", sender, err))
+		return
+	}
+	// we cannot use gob encoding here because the type spec registered relies on message sequence
+	// in other word, it might be not deterministic https://stackoverflow.com/a/33228913/1147187
+	hash := sha256.Sum256(payload)
+	msgWithHash := &P2PMessageWithHash{
+		From:                    sender,
+		Hash:                    hash[:],
+		IsToOldAndNewCommittees: m.IsToOldAndNewCommittees,
+		SenderSignature:         senderSignature,
+		Relayer:                 t.host.ID().Pretty(),
+		Key:                     string(key)}
+	for _, id := range m.To {
+		msgWithHash.To = append(msgWithHash.To, id.Id)
+	}
+	msgWithHash.RelayerSignature = t.sign(msgWithHash.signingBytes())
+
+	t.sanityCheckMtx.Lock()
+	outcome := t.receiveBroadcastGuarded(sender, payload, msgWithHash, m)
+	t.sanityCheckMtx.Unlock()
+	t.applyRoundOutcome(outcome)
+}
+
+// guarded by t.sanityCheckMtx
+func (t *p2pTransporter) receiveBroadcastGuarded(sender string, payload []byte, msgWithHash *P2PMessageWithHash, m *tss.MessageWrapper) roundOutcome {
+	name := roundOf(p2pMessageKey(msgWithHash.Key))
+	if t.verifiedRounds[name] {
+		logger.Warningf("ignored a broadcast from %s after round %s is verified", sender, name)
+		return roundOutcome{}
+	}
+	round := t.broadcastRoundGuarded(name)
+	if received := round.received[sender]; received != nil {
+		if !bytes.Equal(received.Hash, msgWithHash.Hash) {
+			// sender sent us different messages of the same broadcast
+			t.reportBlame([]*P2PMessageWithHash{received, msgWithHash})
+		}
+		return roundOutcome{}
+	}
+	round.received[sender] = msgWithHash
+	round.payloads[sender] = payload
+	if round.recipients == nil {
+		round.numOfSenders = t.numOfBroadcastSenders(sender)
+		round.recipients = make(map[string]bool)
+	}
+	// a sender might not be a recipient of its own broadcast, but it is of others'
+	for recipient := range t.recipientsOf(m) {
+		round.recipients[recipient] = true
+	}
+	var digest []byte
+	if len(round.received) == round.numOfSenders {
+		digest = t.digestGuarded(name, round)
+	}
+	outcome := t.checkBroadcastRoundGuarded(name, round)
+	if digest != nil {
+		outcome.digest = digest
+		for recipient := range round.recipients {
+			outcome.recipients = append(outcome.recipients, recipient)
+		}
+	}
+	return outcome
+}
+
+// handleDigest records what pid received in a round
+func (t *p2pTransporter) handleDigest(pid string, digest *BroadcastDigest) {
+	received := make(map[string]*P2PMessageWithHash)
+	for _, m := range digest.Broadcasts {
+		if m.Relayer != pid {
+			t.reportMisbehavior(pid, fmt.Errorf("dropped a forged digest from %s, claimed relayer: %s", pid, m.Relayer))
+			return
+		}
+		if err := verifySignature(pid, m.signingBytes(), m.RelayerSignature); err != nil {
+			t.reportMisbehavior(pid, fmt.Errorf("dropped a digest not signed by %s: %v", pid, err))
+			return
+		}
+		if roundOf(p2pMessageKey(m.Key)) != digest.Round {
+			t.reportMisbehavior(pid, fmt.Errorf("dropped a digest from %s, broadcast %s is not in round %s", pid, m.Key, digest.Round))
+			return
+		}
+		if _, ok := received[m.From]; ok {
+			t.reportMisbehavior(pid, fmt.Errorf("dropped a digest from %s, it has more than one broadcast from %s", pid, m.From))
+			return
+		}
+		received[m.From] = m
+	}
+
+	t.sanityCheckMtx.Lock()
+	outcome := t.receiveDigestGuarded(pid, digest.Round, received)
+	t.sanityCheckMtx.Unlock()
+	t.applyRoundOutcome(outcome)
+}
+
+// guarded by t.sanityCheckMtx
+func (t *p2pTransporter) receiveDigestGuarded(pid, name string, received map[string]*P2PMessageWithHash) roundOutcome {
+	if t.verifiedRounds[name] {
+		logger.Warningf("ignored another digest of round %s from %s", name, pid)
+		return roundOutcome{}
+	}
+	round := t.broadcastRoundGuarded(name)
+	if _, ok := round.digests[pid]; ok {
+		logger.Warningf("ignored another digest of round %s from %s", name, pid)
+		return roundOutcome{}
+	}
+	round.digests[pid] = received
+	return t.checkBroadcastRoundGuarded(name, round)
+}
+
+// guarded by t.sanityCheckMtx
+func (t *p2pTransporter) broadcastRoundGuarded(name string) *broadcastRound {
+	round, ok := t.broadcastRounds[name]
+	if !ok {
+		round = newBroadcastRound()
+		t.broadcastRounds[name] = round
+	}
+	return round
+}
+
+// digestGuarded returns our digest of the round to send once the lock is released, the round is compared with
+// digests of peers from now on
+// guarded by t.sanityCheckMtx
+func (t *p2pTransporter) digestGuarded(name string, round *broadcastRound) []byte {
+	digest := &BroadcastDigest{Round: name}
+	for _, m := range round.received {
+		digest.Broadcasts = append(digest.Broadcasts, m)
+	}
+	payload, err := proto.Marshal(digest)
+	if err != nil {
+		common.Panic(fmt.Errorf("cannot marshal BroadcastDigest: %v", err))
+	}
+	round.digestSent = true
+	logger.Debugf("digest of %d broadcast(s) in round %s is ready", len(digest.Broadcasts), name)
+	return append([]byte{HashMessagePrefix}, payload...)
+}
+
+// checkBroadcastRoundGuarded compares digests of peers with what we received, both the senders and what they sent, and
+// hands out broadcasts of the round once all of them agree. The round is dropped then.
+// guarded by t.sanityCheckMtx
+func (t *p2pTransporter) checkBroadcastRoundGuarded(name string, round *broadcastRound) roundOutcome {
+	if !round.digestSent {
+		return roundOutcome{}
+	}
+	self := t.host.ID().Pretty()
+	for recipient, digest := range round.digests {
+		for sender, theirs := range digest {
+			ours := round.received[sender]
+			if ours == nil {
+				// we don't keep our own broadcast, anything else we have received as we sent our digest
+				if sender == self {
+					continue
+				}
+				return roundOutcome{culprit: recipient, err: fmt.Errorf("digest of round %s from %s has a broadcast from %s, who doesn't broadcast to us", name, recipient, sender)}
+			}
+			if !bytes.Equal(ours.Hash, theirs.Hash) {
+				t.reportBlame([]*P2PMessageWithHash{ours, theirs})
+				return roundOutcome{}
+			}
+		}
+		// a recipient doesn't relay its own broadcast
+		for sender := range round.received {
+			if _, ok := digest[sender]; !ok && sender != recipient {
+				return roundOutcome{culprit: recipient, err: fmt.Errorf("digest of round %s from %s omits the broadcast from %s", name, recipient, sender)}
+			}
+		}
+	}
+	if len(round.digests) < len(round.recipients) {
+		logger.Debugf("didn't receive enough digests of round %s yet. Expected: %d, Got: %d", name, len(round.recipients), len(round.digests))
+		return roundOutcome{}
+	}
+	delete(t.broadcastRounds, name)
+	t.verifiedRounds[name] = true
+	payloads := make([][]byte, 0, len(round.payloads))
+	for _, payload := range round.payloads {
+		payloads = append(payloads, payload)
+	}
+	logger.Debugf("verified %d broadcast(s) of round %s with %d peer(s)", len(round.received), name, len(round.recipients))
+	return roundOutcome{payloads: payloads}
+}
+
+// applyRoundOutcome sends our digest, delivers verified broadcasts or reports the peer disagreeing with us,
+// it must not hold t.sanityCheckMtx
+func (t *p2pTransporter) applyRoundOutcome(outcome roundOutcome) {
+	for _, recipient := range outcome.recipients {
+		if err := t.Send(outcome.digest, common.TssClientId(recipient)); err != nil {
+			t.reportError(recipient, fmt.Errorf("cannot send digest to %s: %v", recipient, err))
+			return
+		}
+	}
+	if outcome.err != nil {
+		t.reportMisbehavior(outcome.culprit, outcome.err)
+		return
+	}
+	for _, payload := range outcome.payloads {
+		t.receiveCh <- common.P2pMessageWrapper{MessageWrapperBytes: payload}
+	}
+}
+
+// numOfBroadcastSenders is how many parties (except us) broadcast the same type of message as sender, that is its committee in regroup, or all peers
+func (t *p2pTransporter) numOfBroadcastSenders(sender string) int {
+	if t.regroupParams == nil {
+		return len(t.expectedPeers)
+	}
+	self := t.host.ID().Pretty()
+	for _, committee := range []*tss.PeerContext{t.regroupParams.OldParties(), t.regroupParams.NewParties()} {
+		numOfSenders, isSender := 0, false
+		for _, id := range committee.IDs() {
+			if id.Id == sender {
+				isSender = true
+			}
+			if id.Id != self {
+				numOfSenders++
+			}
+		}
+		if isSender {
+			return numOfSenders
+		}
+	}
+	return len(t.expectedPeers)
+}
+
+// recipientsOf returns parties (except us) who receive the broadcast m
+func (t *p2pTransporter) recipientsOf(m *tss.MessageWrapper) map[string]bool {
+	recipients := make(map[string]bool)
+	if len(m.To) == 0 {
+		for _, p := range t.expectedPeers {
+			recipients[p.Pretty()] = true
+		}
+	} else {
+		for _, id := range m.To {
+			recipients[id.Id] = true
+		}
+	}
+	delete(recipients, t.host.ID().Pretty())
+	return recipients
+}
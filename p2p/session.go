@@ -0,0 +1,413 @@
+package p2p
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	swarm "github.com/libp2p/go-libp2p-swarm"
+
+	"github.com/bnb-chain/tss/common"
+)
+
+const (
+	reconnectTimeout     = 5 * time.Minute  // how long we wait for a dropped peer before giving up the session
+	maxRedialInterval    = 30 * time.Second // redial interval doubles from 1 second up to this
+	sessionWriteTimeout  = 30 * time.Second
+	sendQueueSize        = 1024               // how many messages to a peer can be pending (not acknowledged) before Send blocks
+	drainTimeout         = 10 * time.Second   // how long Shutdown waits for peers to acknowledge messages we have sent
+	sequenceLength       = 8                  // big endian uint64
+	sequenceHeaderLength = 1 + sequenceLength // prefix + sequence
+)
+
+type sequencedFrame struct {
+	seq   uint64
+	frame []byte
+}
+
+// peerSession keeps the party stream with one peer alive across reconnections.
+// Messages are numbered and kept until the peer acknowledges them, so that they can be resent on a new stream
+// and the peer can drop those it has already received.
+// Messages are queued and written by writeRoutine of the session, so that a slow peer doesn't hold up others
+type peerSession struct {
+	pid    string
+	dialer bool // whether we redial the peer, the same rule with initial connection: party with smaller id dials
+	codec  common.FrameCodec
+
+	mtx      sync.Mutex
+	stream   network.Stream   // guarded by mtx
+	replaced chan struct{}    // closed when stream is replaced, guarded by mtx
+	nextSeq  uint64           // guarded by mtx
+	outbox   []sequencedFrame // queued or sent but not acknowledged yet, guarded by mtx
+	written  uint64           // highest sequence written to current stream, guarded by mtx
+	broken   network.Stream   // stream failed to write, we wait for it to be replaced, guarded by mtx
+
+	writeMtx sync.Mutex    // serializes writes to stream
+	wake     chan struct{} // tells writeRoutine there is something to write
+	slots    chan struct{} // one for each message in outbox, bounds outbox
+
+	lost         error         // peer cannot be reconnected, guarded by mtx
+	lastReceived uint64        // atomic, highest sequence received from peer
+	finished     chan struct{} // closed when peer says bye, so its dropped stream needn't be reconnected
+	finishOnce   sync.Once
+}
+
+func newPeerSession(pid string, stream network.Stream, dialer bool, codec common.FrameCodec) *peerSession {
+	return &peerSession{
+		pid:      pid,
+		dialer:   dialer,
+		codec:    codec,
+		stream:   stream,
+		replaced: make(chan struct{}),
+		wake:     make(chan struct{}, 1),
+		slots:    make(chan struct{}, sendQueueSize),
+		finished: make(chan struct{}),
+	}
+}
+
+// send numbers msg and queues it for writeRoutine, msg is kept to be resent if the stream is dropped before peer acknowledges it.
+// It blocks while sendQueueSize messages to peer are pending, and gives up if peer doesn't acknowledge any of them within reconnectTimeout
+func (s *peerSession) send(msg []byte) error {
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		logger.Debugf("send queue to %s is full, waiting", s.pid)
+		timer := time.NewTimer(reconnectTimeout)
+		defer timer.Stop()
+		select {
+		case s.slots <- struct{}{}:
+		case <-timer.C:
+			return fmt.Errorf("%d message(s) to %s are not acknowledged within %v", sendQueueSize, s.pid, reconnectTimeout)
+		}
+	}
+
+	s.mtx.Lock()
+	s.nextSeq++
+	frame := make([]byte, sequenceHeaderLength, sequenceHeaderLength+len(msg))
+	frame[0] = SequencedMessagePrefix
+	binary.BigEndian.PutUint64(frame[1:], s.nextSeq)
+	frame = append(frame, msg...)
+	s.outbox = append(s.outbox, sequencedFrame{s.nextSeq, frame})
+	s.mtx.Unlock()
+	s.notify()
+	return nil
+}
+
+func (s *peerSession) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// writeRoutine writes messages in outbox to current stream in order, until closed
+func (s *peerSession) writeRoutine(closed <-chan bool) {
+	for {
+		select {
+		case <-s.wake:
+		case <-closed:
+			return
+		}
+		for s.writeNext() {
+		}
+	}
+}
+
+// writeNext writes the first message not written to current stream yet, returns false if there is nothing to write or the stream is broken
+func (s *peerSession) writeNext() bool {
+	s.mtx.Lock()
+	stream := s.stream
+	if stream == s.broken {
+		s.mtx.Unlock()
+		return false
+	}
+	var next sequencedFrame
+	for _, pending := range s.outbox {
+		if pending.seq > s.written {
+			next = pending
+			break
+		}
+	}
+	s.mtx.Unlock()
+	if next.frame == nil {
+		return false
+	}
+
+	if err := s.write(stream, next.frame); err != nil {
+		// the reader of the stream would notice and wait for reconnection, replace wakes us up then
+		logger.Warningf("failed to send to %s, it will be resent after reconnection: %v", s.pid, err)
+		s.mtx.Lock()
+		s.broken = stream
+		s.mtx.Unlock()
+		stream.Reset()
+		return false
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.stream != stream {
+		// resent from the beginning of outbox on the new stream
+		return true
+	}
+	s.written = next.seq
+	return true
+}
+
+func (s *peerSession) write(stream network.Stream, frame []byte) error {
+	s.writeMtx.Lock()
+	defer s.writeMtx.Unlock()
+	return writeFrame(s.codec, stream, frame)
+}
+
+// received records seq from peer and acknowledges it, returns false if the message has been received (resent after reconnection)
+func (s *peerSession) received(seq uint64) bool {
+	isNew := seq > atomic.LoadUint64(&s.lastReceived)
+	if isNew {
+		atomic.StoreUint64(&s.lastReceived, seq)
+	}
+
+	stream, _ := s.current()
+	if err := s.write(stream, ackFrame(seq)); err != nil {
+		// acknowledgement is sent again on reconnection
+		logger.Debugf("failed to acknowledge %d to %s: %v", seq, s.pid, err)
+	}
+	return isNew
+}
+
+// acknowledged drops messages peer has received from outbox
+func (s *peerSession) acknowledged(seq uint64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	i := 0
+	for i < len(s.outbox) && s.outbox[i].seq <= seq {
+		i++
+		<-s.slots
+	}
+	s.outbox = s.outbox[i:]
+}
+
+// replace switches to a new stream with peer, tells peer what we have received and resends what peer has not acknowledged
+func (s *peerSession) replace(stream network.Stream) {
+	s.mtx.Lock()
+	old := s.stream
+	s.stream = stream
+	close(s.replaced)
+	s.replaced = make(chan struct{})
+	s.written = 0
+	numOfPending := len(s.outbox)
+	s.mtx.Unlock()
+
+	if old != nil && old != stream {
+		old.Reset()
+	}
+	if err := s.write(stream, ackFrame(atomic.LoadUint64(&s.lastReceived))); err != nil {
+		logger.Warningf("failed to resume party stream with %s: %v", s.pid, err)
+		stream.Reset()
+		return
+	}
+	logger.Infof("resumed party stream with %s, resending %d message(s)", s.pid, numOfPending)
+	s.notify()
+}
+
+// current returns current stream and a channel closed once it is replaced
+func (s *peerSession) current() (network.Stream, <-chan struct{}) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.stream, s.replaced
+}
+
+// pending returns how many messages peer hasn't acknowledged, and why they are lost if peer cannot be reconnected
+func (s *peerSession) pending() (int, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.outbox), s.lost
+}
+
+func (s *peerSession) lose(err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.lost = err
+}
+
+func (s *peerSession) finish() {
+	s.finishOnce.Do(func() {
+		close(s.finished)
+	})
+}
+
+func (s *peerSession) isFinished() bool {
+	select {
+	case <-s.finished:
+		return true
+	default:
+		return false
+	}
+}
+
+// bye tells peer we are done, best effort
+func (s *peerSession) bye() {
+	stream, _ := s.current()
+	if err := s.write(stream, []byte{ByeMessagePrefix}); err != nil {
+		logger.Debugf("failed to say bye to %s: %v", s.pid, err)
+	}
+}
+
+// closeWrite closes our side of current stream after bye, peer can still read what we have written
+func (s *peerSession) closeWrite() {
+	stream, _ := s.current()
+	s.writeMtx.Lock()
+	defer s.writeMtx.Unlock()
+	if err := stream.Close(); err != nil {
+		logger.Debugf("failed to close party stream with %s: %v", s.pid, err)
+	}
+}
+
+// waitForReconnection blocks until the broken stream with peer is replaced, we redial the peer if we are the dialer.
+// It returns nil if the transporter is closed or peer has finished
+func (t *p2pTransporter) waitForReconnection(session *peerSession, broken network.Stream, err error) network.Stream {
+	stream, replaced := session.current()
+	if stream != broken {
+		return stream
+	}
+	logger.Warningf("lost party stream with %s: %v, waiting for reconnection", session.pid, err)
+	broken.Reset()
+	if session.dialer {
+		go t.redialRoutine(session, replaced)
+	}
+
+	timer := time.NewTimer(reconnectTimeout)
+	defer timer.Stop()
+	select {
+	case <-replaced:
+		stream, _ = session.current()
+		logger.Infof("reconnected with %s", session.pid)
+		return stream
+	case <-session.finished:
+		return nil
+	case <-t.closed:
+		return nil
+	case <-timer.C:
+		err = fmt.Errorf("cannot reconnect with %s within %v: %v", session.pid, reconnectTimeout, err)
+		session.lose(err)
+		t.reportError(session.pid, err)
+		return nil
+	}
+}
+
+func (t *p2pTransporter) redialRoutine(session *peerSession, replaced <-chan struct{}) {
+	pid, err := peer.IDB58Decode(session.pid)
+	if err != nil {
+		common.Panic(err)
+	}
+	interval := time.Second
+	for {
+		select {
+		case <-replaced:
+			return
+		case <-session.finished:
+			return
+		case <-t.closed:
+			return
+		case <-time.After(interval):
+		}
+
+		t.host.Network().(*swarm.Swarm).Backoff().Clear(pid)
+		stream, err := t.host.NewStream(t.ctx, pid, t.partyProtocol)
+		if err != nil {
+			logger.Debugf("failed to redial %s, will retry in %v: %v", session.pid, interval, err)
+			if interval *= 2; interval > maxRedialInterval {
+				interval = maxRedialInterval
+			}
+			continue
+		}
+		session.replace(stream)
+		return
+	}
+}
+
+// WaitForDelivery waits for peers to acknowledge messages we have sent, so that our last messages are not lost on shutdown.
+// Peers who have finished are not waited as they don't need our messages anymore
+func (t *p2pTransporter) WaitForDelivery(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		pending := make(map[string]int)
+		var lost error
+		t.sessions.Range(func(pid, session interface{}) bool {
+			s := session.(*peerSession)
+			if s.isFinished() {
+				return true
+			}
+			if n, err := s.pending(); err != nil {
+				lost = err
+				return false
+			} else if n > 0 {
+				pending[pid.(string)] = n
+			}
+			return true
+		})
+		if lost != nil {
+			return lost
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("messages are not acknowledged by peers within %v: %v", timeout, pending)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// reportError tells consumer of ControlCh that messages to pid are lost
+func (t *p2pTransporter) reportError(pid string, err error) {
+	logger.Error(err)
+	select {
+	case t.controlCh <- common.ControlMessage{Peer: common.TssClientId(pid), Err: err}:
+	case <-t.closed:
+	}
+}
+
+// reportMisbehavior drops what pid sent and tells consumer of ControlCh, a message it cannot decode or a forged one
+// is not a network problem, so neither reconnection nor resending would help
+func (t *p2pTransporter) reportMisbehavior(pid string, err error) {
+	securityLogger.Warningf("peer %s misbehaved: %v", pid, err)
+	select {
+	case t.controlCh <- common.ControlMessage{Peer: common.TssClientId(pid), Err: err}:
+	case <-t.closed:
+	}
+}
+
+// reportDelivered tells consumer of ControlCh that messages up to seq are acknowledged by pid, it doesn't block
+func (t *p2pTransporter) reportDelivered(pid string, seq uint64) {
+	select {
+	case t.controlCh <- common.ControlMessage{Peer: common.TssClientId(pid), Delivered: seq}:
+	default:
+	}
+}
+
+func ackFrame(seq uint64) []byte {
+	frame := make([]byte, sequenceHeaderLength)
+	frame[0] = AckMessagePrefix
+	binary.BigEndian.PutUint64(frame[1:], seq)
+	return frame
+}
+
+// parseSequence extracts sequence from payload of SequencedMessagePrefix and AckMessagePrefix frames
+func parseSequence(payload []byte) (uint64, error) {
+	if len(payload) < sequenceLength {
+		return 0, fmt.Errorf("frame is too short: %d", len(payload))
+	}
+	return binary.BigEndian.Uint64(payload[:sequenceLength]), nil
+}
+
+// writeFrame writes msg, type prefix followed by payload, as one frame
+func writeFrame(codec common.FrameCodec, stream network.Stream, msg []byte) error {
+	if err := stream.SetWriteDeadline(time.Now().Add(sessionWriteTimeout)); err != nil {
+		return err
+	}
+	defer stream.SetWriteDeadline(time.Time{})
+	return codec.WriteFrame(stream, msg[0], msg[1:])
+}
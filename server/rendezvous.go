@@ -0,0 +1,156 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bnb-chain/tss/common"
+)
+
+const (
+	maxRendezvousPayload    = 4 * 1024
+	maxRendezvousRegistrant = 64 // registrants per topic, a committee is never that large
+	maxRendezvousTopics     = 10000
+	rendezvousTTL           = time.Hour
+	rendezvousGcInterval    = time.Minute
+)
+
+var errRendezvousFull = errors.New("too many rendezvous registrations")
+
+// topic and registrant are hex strings generated by client, topic is derived from channel id and channel password,
+// so that the server learns neither of them and parties using a different password never see each other
+var rendezvousTokenPattern = regexp.MustCompile(`^[0-9a-f]{16,64}$`)
+
+// RendezvousServer is a http service helping parties not in the same LAN find each other before raw tcp bootstrapping
+// PUT  /rendezvous/<topic>/<registrant> - register (or refresh) an encrypted bootstrap message
+// GET  /rendezvous/<topic>              - fetch all registered bootstrap messages under topic
+// Bootstrap messages are opaque to the server, they are encrypted by clients with a key derived from channel password
+type RendezvousServer struct {
+	mtx    sync.Mutex
+	topics map[string]map[string]*RendezvousEntry // topic -> registrant -> entry
+}
+
+type RendezvousEntry struct {
+	Registrant string    `json:"registrant"`
+	Payload    []byte    `json:"payload"`
+	expire     time.Time // entries are removed after expire
+}
+
+func NewRendezvousServer() *RendezvousServer {
+	s := &RendezvousServer{
+		topics: make(map[string]map[string]*RendezvousEntry),
+	}
+	go s.gcRoutine()
+	return s
+}
+
+// ListenAndServe blocks serving rendezvous requests on addr (host:port)
+func (s *RendezvousServer) ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle(common.RendezvousPath, s)
+	logger.Infof("Rendezvous server is listening on: %s", addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+func (s *RendezvousServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, common.RendezvousPath), "/")
+	for _, part := range parts {
+		if !rendezvousTokenPattern.MatchString(part) {
+			http.Error(w, "invalid topic or registrant", http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch {
+	case r.Method == http.MethodPut && len(parts) == 2:
+		payload, err := ioutil.ReadAll(io.LimitReader(r.Body, maxRendezvousPayload+1))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(payload) == 0 || len(payload) > maxRendezvousPayload {
+			http.Error(w, "invalid payload size", http.StatusBadRequest)
+			return
+		}
+		if status, err := s.register(parts[0], parts[1], payload); err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && len(parts) == 1:
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(s.entries(parts[0])); err != nil {
+			logger.Debugf("failed to write rendezvous response: %v", err)
+		}
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func (s *RendezvousServer) register(topic, registrant string, payload []byte) (int, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	registrants, ok := s.topics[topic]
+	if !ok {
+		if len(s.topics) >= maxRendezvousTopics {
+			return http.StatusServiceUnavailable, errRendezvousFull
+		}
+		registrants = make(map[string]*RendezvousEntry)
+		s.topics[topic] = registrants
+	}
+	if _, ok := registrants[registrant]; !ok && len(registrants) >= maxRendezvousRegistrant {
+		return http.StatusForbidden, errRendezvousFull
+	}
+	registrants[registrant] = &RendezvousEntry{
+		Registrant: registrant,
+		Payload:    payload,
+		expire:     time.Now().Add(rendezvousTTL),
+	}
+	logger.Debugf("registered %s under rendezvous topic %s", registrant, topic)
+	return http.StatusNoContent, nil
+}
+
+func (s *RendezvousServer) entries(topic string) []*RendezvousEntry {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := time.Now()
+	entries := make([]*RendezvousEntry, 0, len(s.topics[topic]))
+	for _, entry := range s.topics[topic] {
+		// expired entries might not be collected yet
+		if !now.After(entry.expire) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func (s *RendezvousServer) gcRoutine() {
+	for range time.Tick(rendezvousGcInterval) {
+		s.gc(time.Now())
+	}
+}
+
+// gc removes entries expired by now, and topics left empty
+func (s *RendezvousServer) gc(now time.Time) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for topic, registrants := range s.topics {
+		for registrant, entry := range registrants {
+			if now.After(entry.expire) {
+				delete(registrants, registrant)
+			}
+		}
+		if len(registrants) == 0 {
+			delete(s.topics, topic)
+		}
+	}
+}
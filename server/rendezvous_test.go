@@ -0,0 +1,177 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bnb-chain/tss/common"
+)
+
+func newTestRendezvous(t *testing.T) (*RendezvousServer, *httptest.Server) {
+	s := &RendezvousServer{topics: make(map[string]map[string]*RendezvousEntry)}
+	mux := http.NewServeMux()
+	mux.Handle(common.RendezvousPath, s)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func newTestChannelId(t *testing.T) string {
+	channelId, err := common.NewChannelId(time.Now().Add(time.Hour).Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return channelId
+}
+
+func newTestClient(t *testing.T, ts *httptest.Server, channelId, password string) *common.RendezvousClient {
+	c, err := common.NewRendezvousClient(ts.URL, channelId, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func put(t *testing.T, ts *httptest.Server, topic, registrant string, payload []byte) int {
+	req, err := http.NewRequest(http.MethodPut, ts.URL+common.RendezvousPath+topic+"/"+registrant, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestRendezvousRoundTrip(t *testing.T) {
+	_, ts := newTestRendezvous(t)
+	channelId := newTestChannelId(t)
+	a := newTestClient(t, ts, channelId, "password")
+	b := newTestClient(t, ts, channelId, "password")
+	stranger := newTestClient(t, ts, channelId, "another password")
+
+	if err := a.Register(&common.RendezvousMessage{Moniker: "a", Addrs: []string{"/ip4/10.0.0.1/tcp/27148"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := stranger.Register(&common.RendezvousMessage{Moniker: "stranger"}); err != nil {
+		t.Fatal(err)
+	}
+	// a refreshed registration replaces the previous one
+	if err := b.Register(&common.RendezvousMessage{Moniker: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Register(&common.RendezvousMessage{Moniker: "b", Addrs: []string{"/ip4/10.0.0.2/tcp/27148"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, err := a.Discover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].Moniker != "b" || len(msgs[0].Addrs) != 1 || msgs[0].Addrs[0] != "/ip4/10.0.0.2/tcp/27148" {
+		t.Fatalf("a should only discover refreshed b, got: %+v", msgs)
+	}
+	msgs, err = b.Discover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].Moniker != "a" {
+		t.Fatalf("b should only discover a, got: %+v", msgs)
+	}
+	// a different password derives a different topic
+	msgs, err = stranger.Discover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 0 {
+		t.Fatalf("stranger should not discover parties of another password, got: %+v", msgs)
+	}
+}
+
+func TestRendezvousExpiry(t *testing.T) {
+	s, ts := newTestRendezvous(t)
+	channelId := newTestChannelId(t)
+	a := newTestClient(t, ts, channelId, "password")
+	b := newTestClient(t, ts, channelId, "password")
+	if err := a.Register(&common.RendezvousMessage{Moniker: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	later := time.Now().Add(rendezvousTTL + time.Second)
+	s.mtx.Lock()
+	for _, registrants := range s.topics {
+		for _, entry := range registrants {
+			entry.expire = time.Now().Add(-time.Second)
+		}
+	}
+	s.mtx.Unlock()
+	msgs, err := b.Discover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 0 {
+		t.Fatalf("expired registration should not be discovered before it is collected, got: %+v", msgs)
+	}
+
+	s.gc(later)
+	s.mtx.Lock()
+	topics := len(s.topics)
+	s.mtx.Unlock()
+	if topics != 0 {
+		t.Fatalf("expired topic should be collected, %d left", topics)
+	}
+}
+
+func TestRendezvousLimits(t *testing.T) {
+	s, ts := newTestRendezvous(t)
+	topic := strings.Repeat("ab", 16)
+
+	for _, c := range []struct {
+		name, topic, registrant string
+		payload                 []byte
+		status                  int
+	}{
+		{"empty payload", topic, "0123456789abcdef", nil, http.StatusBadRequest},
+		{"largest payload", topic, "0123456789abcdef", make([]byte, maxRendezvousPayload), http.StatusNoContent},
+		{"oversized payload", topic, "0123456789abcdef", make([]byte, maxRendezvousPayload+1), http.StatusBadRequest},
+		{"short topic", "abcd", "0123456789abcdef", []byte("x"), http.StatusBadRequest},
+		{"upper case registrant", topic, "0123456789ABCDEF", []byte("x"), http.StatusBadRequest},
+		{"missing registrant", topic, "", []byte("x"), http.StatusBadRequest},
+	} {
+		if status := put(t, ts, c.topic, c.registrant, c.payload); status != c.status {
+			t.Fatalf("%s: status should be %d, got %d", c.name, c.status, status)
+		}
+	}
+
+	for i := 1; i < maxRendezvousRegistrant; i++ {
+		if status := put(t, ts, topic, fmt.Sprintf("%016x", i), []byte("x")); status != http.StatusNoContent {
+			t.Fatalf("registrant %d should be accepted, got %d", i, status)
+		}
+	}
+	if status := put(t, ts, topic, fmt.Sprintf("%016x", maxRendezvousRegistrant), []byte("x")); status != http.StatusForbidden {
+		t.Fatalf("registrant beyond limit should be refused, got %d", status)
+	}
+	// refreshing an existing registrant is still allowed
+	if status := put(t, ts, topic, fmt.Sprintf("%016x", 1), []byte("y")); status != http.StatusNoContent {
+		t.Fatalf("existing registrant should be refreshed, got %d", status)
+	}
+
+	for i := len(s.topics); i < maxRendezvousTopics; i++ {
+		if _, err := s.register(fmt.Sprintf("%032x", i), "0123456789abcdef", []byte("x")); err != nil {
+			t.Fatalf("topic %d should be accepted: %v", i, err)
+		}
+	}
+	if status := put(t, ts, strings.Repeat("cd", 16), "0123456789abcdef", []byte("x")); status != http.StatusServiceUnavailable {
+		t.Fatalf("topic beyond limit should be refused, got %d", status)
+	}
+	if status := put(t, ts, topic, fmt.Sprintf("%016x", 2), []byte("z")); status != http.StatusNoContent {
+		t.Fatalf("existing topic should still accept its registrants, got %d", status)
+	}
+}
@@ -2,95 +2,145 @@ package ssdp
 
 import (
 	"fmt"
-	"github.com/koron/go-ssdp"
-	"log"
+	"net"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/koron/go-ssdp"
+
 	"github.com/bnb-chain/tss/client"
 	"github.com/bnb-chain/tss/common"
 )
 
-const serviceType string = "binance:tss"
+const (
+	serviceTypePrefix = "binance:tss:"
+	ssdpKeyDST        = "tss-ssdp-v1"
+	usnPrefix         = "unique:"
+)
 
-// SsdpService helps parties found others' moniker (encrypted within usn) and listen address(host:port)
+// SsdpService helps parties found others' moniker (hashed within usn) and listen address(host:port)
 // before start this service, process should listen on listenAddr
-// Output of this service is PeerAddrs
+// Peers are reported to found callback of CollectPeerAddrs and recorded in PeerAddrs
+// Both service type and usn are keyed hashes derived from channel id and channel password, so that
+// parties of other channels (i.e. concurrent ceremonies in the same LAN) are ignored and monikers are not exposed
 type SsdpService struct {
-	finished         chan bool
-	listenAddrs      string              // comma separated listen address
-	expectedPeers    int                 // how many listen addresses should be collected before exist
-	existingMonikers map[string]struct{} // existing monikers used to filter out already known listen_addr, this used to exclude already known address during regroup
-	usn              string
-	monitor          *ssdp.Monitor
+	finished      chan bool // closed when all expected peers are found
+	stopOnce      sync.Once
+	closed        chan bool // closed when advertising should stop
+	closeOnce     sync.Once
+	listenAddrs   string              // comma separated listen address
+	expectedPeers int                 // how many listen addresses should be collected before exist
+	existingUsns  map[string]struct{} // usns of existing monikers used to filter out already known listen_addr, this used to exclude already known address during regroup
+	serviceType   string
+	usn           string
+	monitor       *ssdp.Monitor
+	found         func(peerAddr string)
 
 	PeerAddrs sync.Map // map[string]string (uuid -> connectable address)
 }
 
+var _ common.Discovery = (*SsdpService)(nil)
+
 // listenAddrs - comma separated listen address
-func NewSsdpService(moniker, vault, listenAddrs string, expectedPeers int, existingMonikers map[string]struct{}) *SsdpService {
+// ifaces - network interfaces to advertise and monitor on, see common.MulticastInterfaces
+func NewSsdpService(moniker, channelId, channelPassword, listenAddrs string, expectedPeers int, existingMonikers map[string]struct{}, ifaces []net.Interface) *SsdpService {
+	// go-ssdp only supports choosing interfaces globally
+	ssdp.Interfaces = ifaces
+
+	key := common.DeriveChannelKey(channelId, channelPassword, ssdpKeyDST)
 	s := &SsdpService{
-		finished:         make(chan bool),
-		listenAddrs:      listenAddrs,
-		expectedPeers:    expectedPeers,
-		existingMonikers: existingMonikers,
-		usn:              fmt.Sprintf("unique:%s_%s", moniker, vault), // TODO: hash this combination to protect privacy
+		finished:      make(chan bool),
+		closed:        make(chan bool),
+		listenAddrs:   listenAddrs,
+		expectedPeers: expectedPeers,
+		existingUsns:  make(map[string]struct{}),
+		serviceType:   serviceTypePrefix + common.ChannelTag(key, "service"),
+		usn:           usnOf(key, moniker),
+	}
+	for moniker := range existingMonikers {
+		s.existingUsns[usnOf(key, moniker)] = struct{}{}
 	}
 	s.monitor = &ssdp.Monitor{
 		Alive:  s.onAlive,
 		Bye:    nil,
 		Search: nil,
 	}
-
-	go s.startAdvertiser()
 	return s
 }
 
-func (s *SsdpService) CollectPeerAddrs() {
-	if err := s.monitor.Start(); err != nil {
-		log.Fatal(err)
+func (s *SsdpService) CollectPeerAddrs(found func(peerAddr string)) error {
+	ad, err := ssdp.Advertise(s.serviceType, s.usn, s.listenAddrs, "", 1800)
+	if err != nil {
+		return fmt.Errorf("failed to start ssdp advertiser: %v", err)
+	}
+	if err := ad.Alive(); err != nil {
+		ad.Close()
+		return fmt.Errorf("failed to advertise via ssdp: %v", err)
 	}
+	go s.advertiseRoutine(ad)
 
-	<-s.finished
-	s.monitor.Close()
+	s.found = found
+	if err := s.monitor.Start(); err != nil {
+		return fmt.Errorf("failed to start ssdp monitor: %v", err)
+	}
+	defer s.monitor.Close()
+	select {
+	case <-s.finished:
+	case <-s.closed:
+		// bootstrapping finished as peers found us earlier than we found them
+	}
+	return nil
 }
 
-func (s *SsdpService) startAdvertiser() {
+func (s *SsdpService) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+	})
+	return nil
+}
 
-	ad, err := ssdp.Advertise(serviceType, s.usn, s.listenAddrs, "", 1800)
-	if err != nil {
-		log.Fatal(err)
-	}
+func (s *SsdpService) advertiseRoutine(ad *ssdp.Advertiser) {
 	// it might be fine we advertise fast,
 	// because the tss process is not a daemon or long-running process
-	aliveTick := time.Tick(500 * time.Millisecond)
-
-	for range aliveTick {
-		ad.Alive()
+	aliveTick := time.NewTicker(500 * time.Millisecond)
+	defer aliveTick.Stop()
+	for {
+		select {
+		case <-s.closed:
+			ad.Bye()
+			ad.Close()
+			return
+		case <-aliveTick.C:
+			if err := ad.Alive(); err != nil {
+				client.Logger.Warningf("failed to advertise via ssdp: %v", err)
+			}
+		}
 	}
-	ad.Bye()
-	ad.Close()
 }
 
 func (s *SsdpService) stop() {
-	s.finished <- true
+	s.stopOnce.Do(func() {
+		close(s.finished)
+	})
 }
 
 func (s *SsdpService) onAlive(m *ssdp.AliveMessage) {
 	client.Logger.Debugf("ssdp onAlive: %v", m)
-	if m.Type != "binance:tss" {
+	// advertisers of other channels (or using a different channel password) have a different service type
+	if m.Type != s.serviceType {
 		return
 	}
 	if m.USN == s.usn ||
-		!strings.HasPrefix(m.USN, "unique:") ||
-		len(m.USN) == len("unique:") {
+		!strings.HasPrefix(m.USN, usnPrefix) ||
+		len(m.USN) == len(usnPrefix) {
 		return
 	}
 	if _, ok := s.PeerAddrs.Load(m.USN); !ok {
 		multiAddrs := strings.Split(m.Location, ",")
 		for _, multiAddr := range multiAddrs {
-			if len(multiAddrs) > 1 && strings.Contains(multiAddr, "127.0.0.1") {
+			multiAddr = strings.TrimSpace(multiAddr)
+			if len(multiAddrs) > 1 && common.IsLoopbackAddr(multiAddr) {
 				continue
 			}
 			_, err := common.ConvertMultiAddrStrToNormalAddr(multiAddr)
@@ -99,9 +149,10 @@ func (s *SsdpService) onAlive(m *ssdp.AliveMessage) {
 			}
 			// only newly found moniker is considered as new peer
 			// TODO: update old peer's listen addr
-			if _, ok := s.existingMonikers[strings.Trim(m.USN, "unique:")]; !ok {
+			if _, ok := s.existingUsns[m.USN]; !ok {
 				s.PeerAddrs.Store(m.USN, multiAddr)
 				client.Logger.Debugf("stored %s (%s)", m.USN, multiAddr)
+				s.found(multiAddr)
 			}
 			break
 		}
@@ -116,3 +167,7 @@ func (s *SsdpService) onAlive(m *ssdp.AliveMessage) {
 		s.stop()
 	}
 }
+
+func usnOf(key []byte, moniker string) string {
+	return usnPrefix + common.ChannelTag(key, "moniker:"+moniker)
+}
@@ -0,0 +1,462 @@
+// Package inproc runs keygen, sign and regroup of a committee as TssClients in one process, talking over a p2p.MemNet
+// or libp2p hosts on loopback, so that ceremonies can be checked end to end without real processes or tbnbcli
+package inproc
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bnb-chain/tss-lib/v2/ecdsa/keygen"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/bnb-chain/tss/client"
+	"github.com/bnb-chain/tss/common"
+	"github.com/bnb-chain/tss/p2p"
+)
+
+const (
+	password           = "123456789"
+	preParamsGenTimout = 10 * time.Minute
+	defaultTimeout     = 10 * time.Minute
+)
+
+// kdfConfig encrypts vaults of a cluster, they are thrown away after test so argon2 is set as cheap as it can be
+var kdfConfig = common.KDFConfig{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   48,
+}
+
+// Party is an identity with a vault under home of cluster, a party joining the new committee in regroup is a new identity
+type Party struct {
+	Moniker string
+	Id      common.TssClientId
+
+	host *p2p.Host // host of vault on loopback, nil on memnet
+}
+
+func (p *Party) expectedPeer() string {
+	return fmt.Sprintf("%s@%s", p.Moniker, p.Id)
+}
+
+// Cluster is a committee whose vaults are kept under Home, regroup replaces it with the new committee
+type Cluster struct {
+	Home      string
+	Parties   []*Party
+	Threshold int
+	Timeout   time.Duration // how long a ceremony can take, beyond which it fails
+	Deadline  time.Time     // ceremonies fail once it is passed regardless of Timeout, e.g. before a test times out
+	// network of next ceremony, a fresh network is created for each ceremony if nil, set it to inject faults
+	Net *p2p.MemNet
+
+	generation int // how many committees there have been, it names parties of a new committee
+	sessions   int // how many ceremonies there have been, it names sessions on hosts
+
+	// ceremonies run as sessions of hosts on loopback (see client.NewSessionTssClient) rather than on Net,
+	// each party has a host with a libp2p identity
+	loopback bool
+	hosts    map[common.TssClientId]*p2p.Host
+}
+
+// NewCluster creates a committee of n parties with threshold t, it has no key until Keygen
+func NewCluster(home string, n, t int) *Cluster {
+	c := &Cluster{
+		Home:      home,
+		Threshold: t,
+		Timeout:   defaultTimeout,
+	}
+	c.Parties, _ = c.newParties(n) // only a party on loopback can fail to be created
+	return c
+}
+
+// NewLoopbackCluster creates a committee of n parties with threshold t whose ceremonies run over libp2p hosts
+// listening on loopback, hosts are kept till Close
+func NewLoopbackCluster(home string, n, t int) (*Cluster, error) {
+	c := &Cluster{
+		Home:      home,
+		Threshold: t,
+		Timeout:   defaultTimeout,
+		loopback:  true,
+		hosts:     make(map[common.TssClientId]*p2p.Host),
+	}
+	var err error
+	if c.Parties, err = c.newParties(n); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close closes hosts of all parties there have been
+func (c *Cluster) Close() {
+	for _, h := range c.hosts {
+		h.Close()
+	}
+}
+
+func (c *Cluster) newParties(n int) ([]*Party, error) {
+	c.generation++
+	parties := make([]*Party, 0, n)
+	for i := 1; i <= n; i++ {
+		party, err := c.newParty(fmt.Sprintf("g%dp%d", c.generation, i))
+		if err != nil {
+			return nil, err
+		}
+		parties = append(parties, party)
+	}
+	return parties, nil
+}
+
+// newParty creates an identity named by moniker, on loopback it is a node key saved in vault and a host of it
+func (c *Cluster) newParty(moniker string) (*Party, error) {
+	if !c.loopback {
+		return &Party{Moniker: moniker, Id: common.TssClientId(moniker)}, nil
+	}
+	privKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := crypto.MarshalPrivateKey(privKey)
+	if err != nil {
+		return nil, err
+	}
+	id, err := peer.IDFromPrivateKey(privKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(path.Join(c.Home, moniker), 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path.Join(c.Home, moniker, "node_key"), bytes, 0600); err != nil {
+		return nil, err
+	}
+	party := &Party{
+		Moniker: moniker,
+		Id:      common.TssClientId(id.Pretty()),
+		host:    p2p.NewHost(c.Home, moniker, &common.P2PConfig{ListenAddr: "/ip4/127.0.0.1/tcp/0"}),
+	}
+	c.hosts[party.Id] = party.host
+	return party, nil
+}
+
+// Party finds party of current committee by moniker
+func (c *Cluster) Party(moniker string) *Party {
+	for _, party := range c.Parties {
+		if party.Moniker == moniker {
+			return party
+		}
+	}
+	return nil
+}
+
+// Monikers returns monikers of the first n parties of current committee, i.e. signers
+func (c *Cluster) Monikers(n int) []string {
+	monikers := make([]string, 0, n)
+	for _, party := range c.Parties[:n] {
+		monikers = append(monikers, party.Moniker)
+	}
+	return monikers
+}
+
+// Keygen generates a key for current committee and checks all parties saved the same public key
+func (c *Cluster) Keygen() (*ecdsa.PublicKey, error) {
+	if err := c.fillPreParams(c.Parties); err != nil {
+		return nil, err
+	}
+	configs := make([]*common.TssConfig, 0, len(c.Parties))
+	for _, party := range c.Parties {
+		config := c.config(party)
+		config.ExpectedPeers, config.PeerAddrs = peers(c.Parties, party)
+		configs = append(configs, config)
+	}
+	if err := c.run(client.KeygenMode, configs); err != nil {
+		return nil, err
+	}
+	return c.PubKey()
+}
+
+// Sign signs message with signers (monikers of current committee) and checks every signer produced a valid signature
+func (c *Cluster) Sign(signers []string, message *big.Int) error {
+	pubKey, err := c.PubKey()
+	if err != nil {
+		return err
+	}
+	configs := make([]*common.TssConfig, 0, len(signers))
+	for _, moniker := range signers {
+		party := c.Party(moniker)
+		if party == nil {
+			return fmt.Errorf("%s is not in committee", moniker)
+		}
+		config := c.config(party)
+		config.ExpectedPeers, config.PeerAddrs = peers(c.Parties, party)
+		config.Signers = signers
+		config.Message = message.String()
+		configs = append(configs, config)
+	}
+	clients, err := c.start(client.SignMode, configs)
+	if err != nil {
+		return err
+	}
+	for idx, tssClient := range clients {
+		signature := tssClient.Signature()
+		if len(signature) != 64 {
+			return fmt.Errorf("%s: signature should be 64 bytes, got %d", configs[idx].Moniker, len(signature))
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pubKey, message.Bytes(), r, s) {
+			return fmt.Errorf("%s: signature %X cannot be verified", configs[idx].Moniker, signature)
+		}
+	}
+	return nil
+}
+
+// Regroup moves the key from signers (monikers of current committee) to a new committee of newN parties with threshold newT,
+// and checks the new committee saved the same public key. The new committee replaces current one once it is done.
+// Parties of current committee named in stay join the new committee, the rest of it are new identities.
+// A staying signer is in both committees, like `tss regroup` it runs another identity in a temporary vault as new committee,
+// which replaces its vault afterwards. A staying party not signing is only in new committee and runs in its vault
+func (c *Cluster) Regroup(signers, stay []string, newN, newT int) error {
+	pubKey, err := c.PubKey()
+	if err != nil {
+		return err
+	}
+	isSigner := make(map[string]bool, len(signers))
+	for _, moniker := range signers {
+		isSigner[moniker] = true
+	}
+	newParties := make([]*Party, 0, newN)
+	for _, moniker := range stay {
+		party := c.Party(moniker)
+		if party == nil {
+			return fmt.Errorf("%s is not in committee", moniker)
+		}
+		if isSigner[moniker] {
+			if party, err = c.newParty(moniker + common.RegroupSuffix); err != nil {
+				return err
+			}
+		}
+		newParties = append(newParties, party)
+	}
+	parties, err := c.newParties(newN - len(stay))
+	if err != nil {
+		return err
+	}
+	newParties = append(newParties, parties...)
+	if err := c.fillPreParams(newParties); err != nil {
+		return err
+	}
+	configs := make([]*common.TssConfig, 0, len(signers)+newN)
+	for _, moniker := range signers {
+		party := c.Party(moniker)
+		if party == nil {
+			return fmt.Errorf("%s is not in committee", moniker)
+		}
+		config := c.regroupConfig(party, newParties, signers, newN, newT)
+		config.IsOldCommittee = true
+		configs = append(configs, config)
+	}
+	for _, party := range newParties {
+		config := c.regroupConfig(party, newParties, signers, newN, newT)
+		config.IsNewCommittee = true
+		configs = append(configs, config)
+	}
+	if err := c.run(client.RegroupMode, configs); err != nil {
+		return err
+	}
+
+	for _, party := range newParties {
+		if moniker := strings.TrimSuffix(party.Moniker, common.RegroupSuffix); moniker != party.Moniker {
+			// the new identity takes over the vault, as `tss regroup` does
+			if err := os.RemoveAll(path.Join(c.Home, moniker)); err != nil {
+				return err
+			}
+			if err := os.Rename(path.Join(c.Home, party.Moniker), path.Join(c.Home, moniker)); err != nil {
+				return err
+			}
+			party.Moniker = moniker
+		}
+	}
+	c.Parties = newParties
+	c.Threshold = newT
+	newPubKey, err := c.PubKey()
+	if err != nil {
+		return err
+	}
+	if newPubKey.X.Cmp(pubKey.X) != 0 || newPubKey.Y.Cmp(pubKey.Y) != 0 {
+		return fmt.Errorf("public key of new committee is different from the old one")
+	}
+	return nil
+}
+
+func (c *Cluster) regroupConfig(party *Party, newParties []*Party, signers []string, newN, newT int) *common.TssConfig {
+	config := c.config(party)
+	config.ExpectedPeers, config.PeerAddrs = peers(c.Parties, party)
+	config.ExpectedNewPeers, config.NewPeerAddrs = peers(newParties, nil)
+	config.Signers = signers
+	config.NewParties = newN
+	config.NewThreshold = newT
+	return config
+}
+
+// PubKey loads public key from vaults of current committee, it fails if they don't agree
+func (c *Cluster) PubKey() (*ecdsa.PublicKey, error) {
+	var pubKey *ecdsa.PublicKey
+	for _, party := range c.Parties {
+		pk, err := common.LoadEcdsaPubkey(c.Home, party.Moniker, password)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", party.Moniker, err)
+		}
+		if pubKey != nil && (pk.X.Cmp(pubKey.X) != 0 || pk.Y.Cmp(pubKey.Y) != 0) {
+			return nil, fmt.Errorf("%s saved a different public key", party.Moniker)
+		}
+		pubKey = pk
+	}
+	return pubKey, nil
+}
+
+func (c *Cluster) config(party *Party) *common.TssConfig {
+	return &common.TssConfig{
+		Id:            party.Id,
+		Moniker:       party.Moniker,
+		Vault:         party.Moniker,
+		Home:          c.Home,
+		Password:      password,
+		AddressPrefix: "bnb",
+		Parties:       len(c.Parties),
+		Threshold:     c.Threshold,
+		KDFConfig:     kdfConfig,
+	}
+}
+
+func (c *Cluster) run(mode client.ClientMode, configs []*common.TssConfig) error {
+	_, err := c.start(mode, configs)
+	return err
+}
+
+// timeout is how long next ceremony can take
+func (c *Cluster) timeout() time.Duration {
+	if !c.Deadline.IsZero() && time.Until(c.Deadline) < c.Timeout {
+		return time.Until(c.Deadline)
+	}
+	return c.Timeout
+}
+
+// start runs clients of configs on a network (or as a session of their hosts on loopback) till they are all done
+func (c *Cluster) start(mode client.ClientMode, configs []*common.TssConfig) ([]*client.TssClient, error) {
+	timeout := c.timeout()
+	net := c.Net
+	if net == nil && !c.loopback {
+		net = p2p.NewMemNet(time.Now().UnixNano())
+	}
+	c.Net = nil
+	c.sessions++
+	sessionId := fmt.Sprintf("%s-%d", mode, c.sessions)
+	// transporters on memnet, clients on loopback create theirs
+	transporters := make([]common.Transporter, len(configs))
+	for idx, config := range configs {
+		if err := os.MkdirAll(path.Join(config.Home, config.Vault), 0700); err != nil {
+			return nil, err
+		}
+		if c.loopback {
+			config.BootstrapTimeout = timeout
+		} else {
+			transporters[idx] = net.Attach(config.Id)
+		}
+	}
+
+	clients := make([]*client.TssClient, len(configs))
+	errs := make([]error, len(configs))
+	wg := sync.WaitGroup{}
+	for idx := range configs {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			var tssClient *client.TssClient
+			var err error
+			if c.loopback {
+				tssClient, err = client.NewSessionTssClient(configs[idx], mode, c.hosts[configs[idx].Id], sessionId)
+			} else {
+				tssClient, err = client.NewTssClient(configs[idx], mode, transporters[idx])
+			}
+			if err != nil {
+				errs[idx] = err
+				return
+			}
+			clients[idx] = tssClient
+			errs[idx] = tssClient.Start()
+		}(idx)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		for idx, err := range errs {
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", configs[idx].Moniker, err)
+			}
+		}
+		return clients, nil
+	case <-time.After(timeout):
+		// clients are stuck, detaching them from network (closing hosts on loopback) stops them from taking more messages
+		for _, transporter := range transporters {
+			if transporter != nil {
+				transporter.Shutdown()
+			}
+		}
+		c.Close()
+		return nil, fmt.Errorf("%s is not finished within %v", mode, timeout)
+	}
+}
+
+// fillPreParams generates pre params of parties ahead, otherwise tss-lib generates them within a timeout which
+// concurrent parties of one process might not meet
+func (c *Cluster) fillPreParams(parties []*Party) error {
+	errs := make(chan error, len(parties))
+	for _, party := range parties {
+		go func(party *Party) {
+			preParams, err := keygen.GeneratePreParams(preParamsGenTimout)
+			if err == nil {
+				err = common.SavePreParams(c.Home, party.Moniker, preParams, kdfConfig, password)
+			}
+			if err != nil {
+				err = fmt.Errorf("%s: failed to generate pre params: %v", party.Moniker, err)
+			}
+			errs <- err
+		}(party)
+	}
+	for range parties {
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// peers lists parties except self in <moniker>@<id>, and their addresses on loopback (nil on memnet)
+func peers(parties []*Party, self *Party) (expectedPeers, addrs []string) {
+	for _, party := range parties {
+		if party == self {
+			continue
+		}
+		expectedPeers = append(expectedPeers, party.expectedPeer())
+		if party.host != nil {
+			addrs = append(addrs, party.host.Addrs()[0].String())
+		}
+	}
+	return expectedPeers, addrs
+}
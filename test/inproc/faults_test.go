@@ -0,0 +1,86 @@
+package inproc
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/bnb-chain/tss/p2p"
+)
+
+// faultyNet is a network with faults a ceremony survives: memnet doesn't retransmit, so only faults that lose nothing
+// are tolerated, dropped or corrupted messages fail the ceremony (see p2p.LinkFaults)
+func faultyNet(seed int64) *p2p.MemNet {
+	net := p2p.NewMemNet(seed)
+	net.SetFaults(p2p.LinkFaults{
+		Delay:         10 * time.Millisecond,
+		Jitter:        20 * time.Millisecond,
+		DuplicateRate: 0.5,
+		ReorderRate:   0.5,
+	})
+	return net
+}
+
+// checkFaultsInjected checks messages between parties were duplicated and reordered, so that a ceremony passing
+// didn't pass by luck
+func checkFaultsInjected(t *testing.T, net *p2p.MemNet, parties []*Party) {
+	var total p2p.LinkStats
+	for _, from := range parties {
+		for _, to := range parties {
+			stats := net.Stats(from.Id, to.Id)
+			total.Duplicated += stats.Duplicated
+			total.Reordered += stats.Reordered
+		}
+	}
+	if total.Duplicated == 0 || total.Reordered == 0 {
+		t.Fatalf("faults should be injected: %+v", total)
+	}
+}
+
+// TestCeremoniesUnderFaults runs every ceremony over a faulty network, including a regroup with a party in each role:
+// a signer leaving (old committee only), a signer staying (both committees), a party not signing that stays
+// (new committee only) and a new identity
+func TestCeremoniesUnderFaults(t *testing.T) {
+	cluster := newTestCluster(t, 3, 1)
+	seed := int64(20261019)
+	// ceremony runs f on a faulty network, parties are the ones taking part in it
+	ceremony := func(name string, f func(t *testing.T) []*Party) {
+		seed++
+		ok := t.Run(name, func(t *testing.T) {
+			net := faultyNet(seed)
+			cluster.Net = net
+			parties := f(t)
+			checkFaultsInjected(t, net, parties)
+		})
+		if !ok {
+			t.FailNow()
+		}
+	}
+
+	ceremony("keygen", func(t *testing.T) []*Party {
+		if _, err := cluster.Keygen(); err != nil {
+			t.Fatal(err)
+		}
+		return cluster.Parties
+	})
+	ceremony("sign", func(t *testing.T) []*Party {
+		sign(t, cluster, cluster.Monikers(2))
+		return cluster.Parties[:2]
+	})
+	ceremony("regroup", func(t *testing.T) []*Party {
+		oldParties := cluster.Parties
+		signers := []string{oldParties[0].Moniker, oldParties[1].Moniker}
+		stay := []string{oldParties[1].Moniker, oldParties[2].Moniker}
+		if err := cluster.Regroup(signers, stay, 3, 1); err != nil {
+			t.Fatal(err)
+		}
+		// the staying signer ran another identity as new committee, which is in cluster.Parties now
+		return append(oldParties[:2:2], cluster.Parties...)
+	})
+	ceremony("sign by new committee", func(t *testing.T) []*Party {
+		if err := cluster.Sign(cluster.Monikers(2), big.NewInt(54321)); err != nil {
+			t.Fatal(err)
+		}
+		return cluster.Parties[:2]
+	})
+}
@@ -0,0 +1,98 @@
+package inproc
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+)
+
+// ceremoniesEnv enables tests running ceremonies, they take minutes (most of it on generating safe primes of each party)
+// which plain `go test ./...` shouldn't
+const ceremoniesEnv = "TSS_INPROC"
+
+func requireCeremonies(t *testing.T) {
+	if os.Getenv(ceremoniesEnv) == "" {
+		t.Skipf("ceremonies take minutes, set %s=1 to run them", ceremoniesEnv)
+	}
+}
+
+// testDeadline leaves a minute before test times out, so that a stuck ceremony fails telling what is stuck
+// rather than test binary panics
+func testDeadline(t *testing.T) time.Time {
+	if deadline, ok := t.Deadline(); ok {
+		return deadline.Add(-time.Minute)
+	}
+	return time.Time{}
+}
+
+func newTestCluster(t *testing.T, n, threshold int) *Cluster {
+	requireCeremonies(t)
+	cluster := NewCluster(t.TempDir(), n, threshold)
+	cluster.Deadline = testDeadline(t)
+	return cluster
+}
+
+func checkPubKey(t *testing.T, cluster *Cluster, expected *ecdsa.PublicKey) {
+	pubKey, err := cluster.PubKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pubKey.X.Cmp(expected.X) != 0 || pubKey.Y.Cmp(expected.Y) != 0 {
+		t.Fatal("public key of committee is different from the one generated by keygen")
+	}
+}
+
+func sign(t *testing.T, cluster *Cluster, signers []string) {
+	if err := cluster.Sign(signers, big.NewInt(12345)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestKeygenSignRegroup generates a key and moves it between committees, every committee should keep the same public
+// key and be able to sign with it. Regroups run in sequence over the same key, each with a different combination of
+// old and new committee
+func TestKeygenSignRegroup(t *testing.T) {
+	cluster := newTestCluster(t, 3, 1)
+	pubKey, err := cluster.Keygen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// any quorum of committee should be able to sign
+	sign(t, cluster, cluster.Monikers(2))
+	sign(t, cluster, cluster.Monikers(3))
+
+	for _, c := range []struct {
+		name string
+		// monikers of current committee, by index
+		signers, stay []int
+		newN, newT    int
+	}{
+		// the party not signing joins new committee, it is only in new committee while signers are only in old
+		{"new-only", []int{0, 1}, []int{2}, 2, 1},
+		// signers stay, they are in both committees
+		{"both", []int{0, 1}, []int{0, 1}, 3, 1},
+		// signers leave, the key is moved to new parties only
+		{"old-only", []int{1, 2}, nil, 2, 1},
+	} {
+		ok := t.Run(c.name, func(t *testing.T) {
+			monikers := func(idxes []int) []string {
+				result := make([]string, 0, len(idxes))
+				for _, idx := range idxes {
+					result = append(result, cluster.Parties[idx].Moniker)
+				}
+				return result
+			}
+			if err := cluster.Regroup(monikers(c.signers), monikers(c.stay), c.newN, c.newT); err != nil {
+				t.Fatal(err)
+			}
+			checkPubKey(t, cluster, pubKey)
+			sign(t, cluster, cluster.Monikers(c.newT+1))
+		})
+		if !ok {
+			// later regroups rely on the committee of this one
+			return
+		}
+	}
+}
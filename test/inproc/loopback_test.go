@@ -0,0 +1,24 @@
+package inproc
+
+import (
+	"testing"
+)
+
+// TestKeygenSignOverHosts runs ceremonies as sessions of libp2p hosts on loopback, i.e. what a process embedding
+// client.NewSessionTssClient does, each ceremony is another session on the same hosts
+func TestKeygenSignOverHosts(t *testing.T) {
+	requireCeremonies(t)
+	cluster, err := NewLoopbackCluster(t.TempDir(), 3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	cluster.Deadline = testDeadline(t)
+	pubKey, err := cluster.Keygen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign(t, cluster, cluster.Monikers(2))
+	sign(t, cluster, cluster.Monikers(3))
+	checkPubKey(t, cluster, pubKey)
+}